@@ -0,0 +1,298 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/ayildirim21/numaflow-perfman/metrics"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+// AutoscalingSample is the state of one vertex at one point of a run
+type AutoscalingSample struct {
+	Time     time.Time `json:"time"`
+	Replicas int64     `json:"replicas"`
+	Pending  Metric    `json:"pending"`
+	Rate     Metric    `json:"rate"`
+}
+
+// AutoscalingObserver is an Observer recording the replica timeline of every vertex, from the status of its
+// Vertex resource, next to its pending messages and read rate
+type AutoscalingObserver struct {
+	dynamicClient *dynamic.DynamicClient
+	prom          *metrics.PrometheusClient
+	namespace     string
+	interval      time.Duration
+	log           *zap.Logger
+
+	mu          sync.Mutex
+	pipeline    string
+	maxReplicas map[string]int64
+	timelines   map[string][]AutoscalingSample
+}
+
+// NewAutoscalingObserver returns an observer sampling every vertex once every interval
+func NewAutoscalingObserver(dynamicClient *dynamic.DynamicClient, prom *metrics.PrometheusClient, namespace string, interval time.Duration, log *zap.Logger) *AutoscalingObserver {
+	return &AutoscalingObserver{
+		dynamicClient: dynamicClient,
+		prom:          prom,
+		namespace:     namespace,
+		interval:      interval,
+		log:           log,
+		maxReplicas:   map[string]int64{},
+		timelines:     map[string][]AutoscalingSample{},
+	}
+}
+
+// Observe samples every interval until the measurement window closes, recording every replica change as an event
+func (o *AutoscalingObserver) Observe(ctx context.Context, rec *RunRecord) error {
+	o.mu.Lock()
+	o.pipeline = rec.Pipeline
+	o.mu.Unlock()
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		// A failed sample only leaves a gap in the timeline
+		if err := o.sample(ctx, rec, time.Now()); err != nil && ctx.Err() == nil {
+			o.log.Warn("Unable to sample autoscaling", zap.String("pipeline", rec.Pipeline), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *AutoscalingObserver) sample(ctx context.Context, rec *RunRecord, at time.Time) error {
+	vertices, err := o.dynamicClient.Resource(pipeline.VertexGVR).Namespace(o.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", util.PipelineNameLabel, rec.Pipeline),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list vertices of pipeline %s: %w", rec.Pipeline, err)
+	}
+
+	rateWindow := max(o.interval, time.Minute)
+	pending, err := o.byVertex(ctx, fmt.Sprintf(`sum by (vertex) (vertex_pending_messages{namespace="%s", pipeline="%s", period="default"})`,
+		o.namespace, rec.Pipeline), at)
+	if err != nil {
+		return err
+	}
+	rates, err := o.byVertex(ctx, fmt.Sprintf("sum by (vertex) (rate(forwarder_read_total%s[%ds]))",
+		metrics.PipelineSelector(o.namespace, rec.Pipeline), int(rateWindow.Seconds())), at)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, v := range vertices.Items {
+		name := v.GetLabels()[util.VertexNameLabel]
+		replicas, _, _ := unstructured.NestedInt64(v.Object, "status", "replicas")
+		if maxReplicas, found, _ := unstructured.NestedInt64(v.Object, "spec", "scale", "max"); found {
+			o.maxReplicas[name] = maxReplicas
+		}
+
+		s := AutoscalingSample{Time: at, Replicas: replicas, Pending: metricOrNaN(pending, name), Rate: metricOrNaN(rates, name)}
+		timeline := o.timelines[name]
+		if n := len(timeline); n > 0 && timeline[n-1].Replicas != replicas {
+			rec.AddEvent(Event{Time: at, Type: "autoscale", Target: name,
+				Detail: fmt.Sprintf("replicas changed from %d to %d", timeline[n-1].Replicas, replicas)})
+			o.log.Info("Vertex scaled", zap.String("pipeline", rec.Pipeline), zap.String("vertex", name),
+				zap.Int64("from", timeline[n-1].Replicas), zap.Int64("to", replicas))
+		}
+		o.timelines[name] = append(timeline, s)
+	}
+
+	return nil
+}
+
+func (o *AutoscalingObserver) byVertex(ctx context.Context, query string, at time.Time) (map[string]float64, error) {
+	samples, err := o.prom.Query(ctx, query, at)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]float64, len(samples))
+	for _, s := range samples {
+		values[s.Labels["vertex"]] = s.Value
+	}
+
+	return values, nil
+}
+
+func metricOrNaN(values map[string]float64, key string) Metric {
+	if v, ok := values[key]; ok {
+		return Metric(v)
+	}
+
+	return Metric(math.NaN())
+}
+
+// VertexAutoscaling summarizes how the autoscaler scaled one vertex over a run.
+// Reaction times are only as precise as the sampling interval.
+type VertexAutoscaling struct {
+	Vertex string `json:"vertex"`
+	// MaxReplicas is the vertex's scale.max, zero if it is not set
+	MaxReplicas  int64 `json:"maxReplicas"`
+	PeakReplicas int64 `json:"peakReplicas"`
+	ScaleUps     int   `json:"scaleUps"`
+	ScaleDowns   int   `json:"scaleDowns"`
+	// ScaleUpReactions are the times from pending messages first exceeding the threshold until the replicas first rose
+	ScaleUpReactions []time.Duration `json:"scaleUpReactions"`
+	// ScaleDownReactions are the times from pending messages first dropping to the threshold until the replicas first fell
+	ScaleDownReactions []time.Duration `json:"scaleDownReactions"`
+	// Oscillations counts how often the direction of scaling reversed
+	Oscillations int `json:"oscillations"`
+	// TimeAtMax is how long the vertex ran at MaxReplicas
+	TimeAtMax time.Duration       `json:"timeAtMax"`
+	Timeline  []AutoscalingSample `json:"timeline"`
+}
+
+// AutoscalingSummary is the autoscaling behavior of every vertex of a pipeline over a run
+type AutoscalingSummary struct {
+	Pipeline         string              `json:"pipeline"`
+	PendingThreshold float64             `json:"pendingThreshold"`
+	Vertices         []VertexAutoscaling `json:"vertices"`
+}
+
+// Summary analyzes the timelines sampled so far. A backlog begins when pending messages exceed
+// pendingThreshold and clears when they drop to it again.
+func (o *AutoscalingObserver) Summary(pendingThreshold float64) *AutoscalingSummary {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	summary := &AutoscalingSummary{Pipeline: o.pipeline, PendingThreshold: pendingThreshold}
+	for name, timeline := range o.timelines {
+		v := analyzeAutoscaling(timeline, pendingThreshold)
+		v.Vertex, v.MaxReplicas = name, o.maxReplicas[name]
+		if v.MaxReplicas > 0 {
+			for i := 0; i+1 < len(timeline); i++ {
+				if timeline[i].Replicas >= v.MaxReplicas {
+					v.TimeAtMax += timeline[i+1].Time.Sub(timeline[i].Time)
+				}
+			}
+		}
+		summary.Vertices = append(summary.Vertices, v)
+	}
+	sort.Slice(summary.Vertices, func(i, j int) bool { return summary.Vertices[i].Vertex < summary.Vertices[j].Vertex })
+
+	return summary
+}
+
+func analyzeAutoscaling(timeline []AutoscalingSample, pendingThreshold float64) VertexAutoscaling {
+	v := VertexAutoscaling{Timeline: timeline}
+
+	var backlogSince, clearedSince time.Time
+	var reactedUp, reactedDown bool
+	direction := 0
+	for i, s := range timeline {
+		v.PeakReplicas = max(v.PeakReplicas, s.Replicas)
+
+		// NaN pending, i.e. no data, neither begins nor clears a backlog
+		switch {
+		case float64(s.Pending) > pendingThreshold && backlogSince.IsZero():
+			backlogSince, clearedSince, reactedUp = s.Time, time.Time{}, false
+		case float64(s.Pending) <= pendingThreshold && clearedSince.IsZero():
+			clearedSince, backlogSince, reactedDown = s.Time, time.Time{}, false
+		}
+
+		if i == 0 || s.Replicas == timeline[i-1].Replicas {
+			continue
+		}
+
+		step := 1
+		if s.Replicas < timeline[i-1].Replicas {
+			step = -1
+		}
+		if direction != 0 && step != direction {
+			v.Oscillations++
+		}
+		direction = step
+
+		if step > 0 {
+			v.ScaleUps++
+			if !backlogSince.IsZero() && !reactedUp {
+				v.ScaleUpReactions = append(v.ScaleUpReactions, s.Time.Sub(backlogSince))
+				reactedUp = true
+			}
+		} else {
+			v.ScaleDowns++
+			if !clearedSince.IsZero() && !reactedDown {
+				v.ScaleDownReactions = append(v.ScaleDownReactions, s.Time.Sub(clearedSince))
+				reactedDown = true
+			}
+		}
+	}
+
+	return v
+}
+
+// Save writes the summary, including the timelines, as json into dir, returning the path of the file written
+func (summary *AutoscalingSummary) Save(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create autoscaling summary directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal autoscaling summary: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("autoscaling-%s-%s.json", summary.Pipeline, time.Now().UTC().Format("20060102-150405")))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write autoscaling summary: %w", err)
+	}
+
+	return path, nil
+}
+
+// WriteAutoscalingTable prints the autoscaling behavior of every vertex
+func WriteAutoscalingTable(out io.Writer, summary *AutoscalingSummary) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "vertex\tmax\tpeak\tscale ups\tscale downs\tup reaction (s)\tdown reaction (s)\toscillations\ttime at max")
+	for _, v := range summary.Vertices {
+		maxReplicas := "-"
+		if v.MaxReplicas > 0 {
+			maxReplicas = fmt.Sprint(v.MaxReplicas)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t%s\t%d\t%s\n", v.Vertex, maxReplicas, v.PeakReplicas, v.ScaleUps, v.ScaleDowns,
+			formatReactions(v.ScaleUpReactions), formatReactions(v.ScaleDownReactions), v.Oscillations, v.TimeAtMax.Round(time.Second))
+	}
+
+	return w.Flush()
+}
+
+// formatReactions prints the mean and maximum of reaction times as "mean / max"
+func formatReactions(reactions []time.Duration) string {
+	if len(reactions) == 0 {
+		return "-"
+	}
+
+	var sum, longest time.Duration
+	for _, r := range reactions {
+		sum += r
+		longest = max(longest, r)
+	}
+
+	return fmt.Sprintf("%s / %s", formatMetric((sum/time.Duration(len(reactions))).Seconds(), 0), formatMetric(longest.Seconds(), 0))
+}
@@ -0,0 +1,283 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/ayildirim21/numaflow-perfman/metrics"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+)
+
+// Numaflow's limits for buffers that neither their vertex nor the pipeline sets
+const (
+	defaultBufferMaxLength  = 30000
+	defaultBufferUsageLimit = 80
+)
+
+// BackpressureOptions configures the analysis of backpressure in front of one vertex
+type BackpressureOptions struct {
+	// Vertex is the slow vertex whose input buffers are expected to fill
+	Vertex string
+	// Step is the resolution of the timelines
+	Step time.Duration
+	// MinRateDrop is the fraction by which the upstream rate must fall from its peak for backpressure to count as engaged
+	MinRateDrop float64
+}
+
+// BufferUsage is how full one partition of the buffer in front of the vertex was over the window
+type BufferUsage struct {
+	Buffer string `json:"buffer"`
+	// Peak is the highest fraction of the buffer in use
+	Peak Metric `json:"peak"`
+	// TimeAtLimit is how long usage stayed at or above the usage limit, during which writers back off
+	TimeAtLimit time.Duration   `json:"timeAtLimit"`
+	Timeline    []metrics.Point `json:"timeline"`
+}
+
+// BackpressureReport shows whether backpressure engaged in front of a vertex over a measurement window
+type BackpressureReport struct {
+	Pipeline string   `json:"pipeline"`
+	Vertex   string   `json:"vertex"`
+	Upstream []string `json:"upstream"`
+	// BufferMaxLength and BufferUsageLimit are the limits of the buffer in front of the vertex, the usage limit as a fraction
+	BufferMaxLength  int64         `json:"bufferMaxLength"`
+	BufferUsageLimit float64       `json:"bufferUsageLimit"`
+	Buffers          []BufferUsage `json:"buffers"`
+	// UpstreamRate is the rate at which the upstream vertices wrote, VertexRate the rate at which the vertex read
+	UpstreamRate      []metrics.Point `json:"upstreamRate"`
+	VertexRate        []metrics.Point `json:"vertexRate"`
+	PeakUpstreamRate  Metric          `json:"peakUpstreamRate"`
+	FinalUpstreamRate Metric          `json:"finalUpstreamRate"`
+	FinalVertexRate   Metric          `json:"finalVertexRate"`
+	// Engaged is true when a buffer reached its usage limit and the upstream rate fell by at least MinRateDrop from its peak
+	Engaged bool `json:"engaged"`
+}
+
+// BackpressureUpstream returns the vertices writing to the buffers in front of the vertex. It fails if the pipeline
+// has no such vertex or the vertex has no upstream vertices, as there is then no buffer to fill.
+func BackpressureUpstream(g *pipeline.Graph, vertex string) ([]string, error) {
+	if g.Vertex(vertex) == nil {
+		return nil, fmt.Errorf("pipeline %s has no vertex %s", g.Name, vertex)
+	}
+
+	var upstream []string
+	for _, e := range g.Edges {
+		if e.To == vertex {
+			upstream = append(upstream, e.From)
+		}
+	}
+	if len(upstream) == 0 {
+		return nil, fmt.Errorf("vertex %s has no upstream vertices, it has no buffer to fill", vertex)
+	}
+
+	return upstream, nil
+}
+
+// AnalyzeBackpressure reports buffer usage in front of the vertex, from the JetStream consumer metrics of its
+// streams, next to the upstream write rate and the vertex's read rate over [start, end].
+// Final rates are averaged over the last quarter of the window.
+func AnalyzeBackpressure(ctx context.Context, prom *metrics.PrometheusClient, namespace string, obj *unstructured.Unstructured, opts BackpressureOptions, start time.Time, end time.Time) (*BackpressureReport, error) {
+	g, err := pipeline.ParseGraph(obj)
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := BackpressureUpstream(g, opts.Vertex)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BackpressureReport{Pipeline: g.Name, Vertex: opts.Vertex, Upstream: upstream}
+
+	maxLength, usageLimit := bufferLimits(g, opts.Vertex)
+	report.BufferMaxLength, report.BufferUsageLimit = maxLength, usageLimit
+
+	// Numaflow names the stream of each buffer partition <namespace>-<pipeline>-<vertex>-<partition>
+	streams := fmt.Sprintf(`{stream_name=~"%s-%s-%s-[0-9]+"}`, namespace, g.Name, opts.Vertex)
+	usageQuery := fmt.Sprintf("(sum by (stream_name) (nats_consumer_num_pending%s) + sum by (stream_name) (nats_consumer_num_ack_pending%s)) / %d",
+		streams, streams, maxLength)
+	usage, err := prom.QueryRange(ctx, usageQuery, start, end, opts.Step)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range usage {
+		b := BufferUsage{Buffer: s.Labels["stream_name"], Peak: Metric(peak(s.Points)), Timeline: s.Points}
+		for i := 0; i+1 < len(s.Points); i++ {
+			if s.Points[i].Value >= usageLimit {
+				b.TimeAtLimit += s.Points[i+1].Time.Sub(s.Points[i].Time)
+			}
+		}
+		report.Buffers = append(report.Buffers, b)
+	}
+	sort.Slice(report.Buffers, func(i, j int) bool { return report.Buffers[i].Buffer < report.Buffers[j].Buffer })
+
+	rateWindow := max(opts.Step, time.Minute)
+	if report.UpstreamRate, err = singleSeries(ctx, prom, metrics.WriteRateQuery(namespace, g.Name, report.Upstream, rateWindow), start, end, opts.Step); err != nil {
+		return nil, err
+	}
+	if report.VertexRate, err = singleSeries(ctx, prom, metrics.ReadRateQuery(namespace, g.Name, []string{opts.Vertex}, rateWindow), start, end, opts.Step); err != nil {
+		return nil, err
+	}
+	report.PeakUpstreamRate = Metric(peak(report.UpstreamRate))
+
+	finalWindow := end.Sub(start) / 4
+	finalUpstream, err := prom.ScalarOrNaN(ctx, metrics.WriteRateQuery(namespace, g.Name, report.Upstream, finalWindow), end)
+	if err != nil {
+		return nil, err
+	}
+	finalVertex, err := prom.ScalarOrNaN(ctx, metrics.ReadRateQuery(namespace, g.Name, []string{opts.Vertex}, finalWindow), end)
+	if err != nil {
+		return nil, err
+	}
+	report.FinalUpstreamRate, report.FinalVertexRate = Metric(finalUpstream), Metric(finalVertex)
+
+	reachedLimit := false
+	for _, b := range report.Buffers {
+		reachedLimit = reachedLimit || float64(b.Peak) >= usageLimit
+	}
+	report.Engaged = reachedLimit && finalUpstream <= float64(report.PeakUpstreamRate)*(1-opts.MinRateDrop)
+
+	return report, nil
+}
+
+// bufferLimits returns the maximum length and the usage limit, as a fraction, of the buffer in front of a vertex.
+// The vertex's own limits take precedence over the pipeline's.
+func bufferLimits(g *pipeline.Graph, vertex string) (int64, float64) {
+	maxLength, usageLimit := int64(defaultBufferMaxLength), int64(defaultBufferUsageLimit)
+	vertexLimits, _, _ := unstructured.NestedMap(g.Vertex(vertex).Spec, "limits")
+	for _, limits := range []map[string]interface{}{g.Limits, vertexLimits} {
+		if v, found := limits["bufferMaxLength"]; found {
+			maxLength = toInt64(v, maxLength)
+		}
+		if v, found := limits["bufferUsageLimit"]; found {
+			usageLimit = toInt64(v, usageLimit)
+		}
+	}
+
+	return maxLength, float64(usageLimit) / 100
+}
+
+func toInt64(v interface{}, fallback int64) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	}
+
+	return fallback
+}
+
+// singleSeries runs a range query expected to return at most one series and returns its points
+func singleSeries(ctx context.Context, prom *metrics.PrometheusClient, query string, start time.Time, end time.Time, step time.Duration) ([]metrics.Point, error) {
+	series, err := prom.QueryRange(ctx, query, start, end, step)
+	if err != nil || len(series) == 0 {
+		return nil, err
+	}
+
+	return series[0].Points, nil
+}
+
+func peak(points []metrics.Point) float64 {
+	highest := math.NaN()
+	for _, p := range points {
+		if math.IsNaN(highest) || p.Value > highest {
+			highest = p.Value
+		}
+	}
+
+	return highest
+}
+
+// Save writes the report as json into dir, returning the path of the file written
+func (report *BackpressureReport) Save(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create backpressure report directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal backpressure report: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("backpressure-%s-%s.json", report.Pipeline, time.Now().UTC().Format("20060102-150405")))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write backpressure report: %w", err)
+	}
+
+	return path, nil
+}
+
+// WriteBackpressureReport prints the peak usage of every buffer, the timeline of usage and rates, and the verdict
+func WriteBackpressureReport(out io.Writer, report *BackpressureReport) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "buffer\tpeak usage (%%)\ttime at %.0f%% limit\n", report.BufferUsageLimit*100)
+	for _, b := range report.Buffers {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", b.Buffer, formatMetric(float64(b.Peak)*100, 1), b.TimeAtLimit.Round(time.Second))
+	}
+	fmt.Fprintln(w)
+
+	// Every range query shares start, end and step, so their points line up by timestamp
+	header := []string{"time"}
+	columns := make([]map[int64]float64, 0, len(report.Buffers)+2)
+	addColumn := func(name string, points []metrics.Point, scale float64) {
+		header = append(header, name)
+		values := make(map[int64]float64, len(points))
+		for _, p := range points {
+			values[p.Time.Unix()] = p.Value * scale
+		}
+		columns = append(columns, values)
+	}
+	for _, b := range report.Buffers {
+		addColumn(b.Buffer+" (%)", b.Timeline, 100)
+	}
+	addColumn(strings.Join(report.Upstream, ",")+" write (msg/s)", report.UpstreamRate, 1)
+	addColumn(report.Vertex+" read (msg/s)", report.VertexRate, 1)
+
+	var times []int64
+	seen := map[int64]bool{}
+	for _, c := range columns {
+		for t := range c {
+			if !seen[t] {
+				seen[t] = true
+				times = append(times, t)
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, t := range times {
+		row := []string{time.Unix(t, 0).Format(time.TimeOnly)}
+		for _, c := range columns {
+			v, ok := c[t]
+			if !ok {
+				v = math.NaN()
+			}
+			row = append(row, formatMetric(v, 1))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	verdict := "NOT ENGAGED"
+	if report.Engaged {
+		verdict = "ENGAGED"
+	}
+	_, err := fmt.Fprintf(out, "\nupstream rate: peak %s msg/s, final %s msg/s; %s read rate: final %s msg/s\nbackpressure: %s\n",
+		formatMetric(float64(report.PeakUpstreamRate), 1), formatMetric(float64(report.FinalUpstreamRate), 1),
+		report.Vertex, formatMetric(float64(report.FinalVertexRate), 1), verdict)
+	return err
+}
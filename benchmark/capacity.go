@@ -0,0 +1,259 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/ayildirim21/numaflow-perfman/metrics"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+)
+
+// Metrics that can limit the throughput a pipeline sustains
+const (
+	LimitPendingGrowth = "pending growth"
+	LimitAckRate       = "ack rate"
+	LimitLatency       = "p99 latency"
+)
+
+// CapacityOptions configures the search for the maximum sustainable rate of a pipeline
+type CapacityOptions struct {
+	// Vertex is the generator source whose rpu is searched, by default the pipeline's only generator
+	Vertex string
+	// MinRPU and MaxRPU bound the search
+	MinRPU int64
+	MaxRPU int64
+	// Precision stops the search once the sustained and unsustained rates are this close
+	Precision int64
+	// Settle is how long each rate runs before it is measured, Hold how long it is measured for
+	Settle time.Duration
+	Hold   time.Duration
+	// MaxPendingGrowth is the largest growth of pending messages, per second, a sustained rate may cause
+	MaxPendingGrowth float64
+	// MinAckRatio is the smallest ratio of ack rate to read rate a sustained rate may cause
+	MinAckRatio float64
+	// MaxP99 is the largest p99 latency, in milliseconds, a sustained rate may cause; zero disables the check
+	MaxP99 float64
+}
+
+// CapacityLevel is the measurement of one rate tried during the search
+type CapacityLevel struct {
+	RPU           int64
+	Sustained     bool
+	Limit         string
+	ReadRate      float64
+	AckRate       float64
+	PendingGrowth float64
+	P99           float64
+}
+
+// CapacityResult is the outcome of a capacity search
+type CapacityResult struct {
+	Record *RunRecord
+	// MaxSustainedRPU is the highest rate sustained, zero if not even MinRPU was
+	MaxSustainedRPU int64
+	// LimitingMetric is the metric that failed at the lowest unsustained rate, empty if MaxRPU was sustained
+	LimitingMetric string
+	Levels         []CapacityLevel
+}
+
+// FindCapacity creates the pipeline and binary searches the rpu of its generator for the highest rate
+// the pipeline sustains, then deletes the pipeline
+func (r *Runner) FindCapacity(ctx context.Context, obj *unstructured.Unstructured, prom *metrics.PrometheusClient, opts CapacityOptions) (result *CapacityResult, err error) {
+	if opts.MinRPU <= 0 || opts.MaxRPU < opts.MinRPU {
+		return nil, fmt.Errorf("invalid rpu range [%d, %d]", opts.MinRPU, opts.MaxRPU)
+	}
+
+	g, err := pipeline.ParseGraph(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	vertex, err := resolveGenerator(g, opts.Vertex)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := r.Start(ctx, obj)
+	if record == nil {
+		return nil, err
+	}
+
+	defer func() {
+		if teardownErr := r.Teardown(record); teardownErr != nil && err == nil {
+			err = teardownErr
+		}
+	}()
+
+	if err != nil {
+		return nil, err
+	}
+
+	record.WindowStart = time.Now()
+	search := capacitySearch{runner: r, prom: prom, opts: opts, graph: g, vertex: vertex, record: record}
+	result, err = search.run(ctx)
+	record.WindowEnd = time.Now()
+
+	return result, err
+}
+
+type capacitySearch struct {
+	runner *Runner
+	prom   *metrics.PrometheusClient
+	opts   CapacityOptions
+	graph  *pipeline.Graph
+	vertex string
+	record *RunRecord
+	levels []CapacityLevel
+}
+
+func (s *capacitySearch) run(ctx context.Context) (*CapacityResult, error) {
+	result := &CapacityResult{Record: s.record}
+
+	low, err := s.try(ctx, s.opts.MinRPU)
+	if err != nil {
+		return nil, err
+	}
+	if !low.Sustained {
+		result.LimitingMetric = low.Limit
+		result.Levels = s.levels
+		return result, nil
+	}
+
+	high, err := s.try(ctx, s.opts.MaxRPU)
+	if err != nil {
+		return nil, err
+	}
+	if high.Sustained {
+		result.MaxSustainedRPU = high.RPU
+		result.Levels = s.levels
+		return result, nil
+	}
+
+	precision := max(s.opts.Precision, 1)
+	for high.RPU-low.RPU > precision {
+		mid, err := s.try(ctx, low.RPU+(high.RPU-low.RPU)/2)
+		if err != nil {
+			return nil, err
+		}
+		if mid.Sustained {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	result.MaxSustainedRPU = low.RPU
+	result.LimitingMetric = high.Limit
+	result.Levels = s.levels
+	return result, nil
+}
+
+// try holds the pipeline at rpu and checks whether it keeps up
+func (s *capacitySearch) try(ctx context.Context, rpu int64) (CapacityLevel, error) {
+	log := s.runner.Log
+	name := s.record.Pipeline
+	if err := pipeline.UpdateVertex(ctx, s.runner.DynamicClient, s.runner.Namespace, name, s.vertex, pipeline.SetGeneratorRPU(rpu)); err != nil {
+		return CapacityLevel{}, err
+	}
+	log.Info("Trying rate", zap.String("pipeline", name), zap.Int64("rpu", rpu))
+
+	if err := sleep(ctx, s.opts.Settle); err != nil {
+		return CapacityLevel{}, err
+	}
+	start := time.Now()
+	if err := sleep(ctx, s.opts.Hold); err != nil {
+		return CapacityLevel{}, err
+	}
+	end := time.Now()
+
+	level, err := s.measure(ctx, rpu, start, end)
+	if err != nil {
+		return CapacityLevel{}, err
+	}
+
+	detail := fmt.Sprintf("rpu %d sustained", rpu)
+	if !level.Sustained {
+		detail = fmt.Sprintf("rpu %d not sustained, limited by %s", rpu, level.Limit)
+	}
+	s.record.AddEvent(Event{Time: end, Type: "capacity", Target: s.vertex, Detail: detail})
+	log.Info("Measured rate", zap.String("pipeline", name), zap.Int64("rpu", rpu), zap.Bool("sustained", level.Sustained),
+		zap.String("limit", level.Limit), zap.Float64("read-rate", level.ReadRate), zap.Float64("ack-rate", level.AckRate),
+		zap.Float64("pending-growth", level.PendingGrowth), zap.Float64("p99-ms", level.P99))
+
+	s.levels = append(s.levels, level)
+	return level, nil
+}
+
+func (s *capacitySearch) measure(ctx context.Context, rpu int64, start time.Time, end time.Time) (CapacityLevel, error) {
+	ns, name := s.runner.Namespace, s.record.Pipeline
+	window := end.Sub(start)
+	level := CapacityLevel{RPU: rpu, Sustained: true}
+
+	var buffered []string
+	for _, v := range s.graph.Vertices {
+		if v.Kind != pipeline.VertexSource {
+			buffered = append(buffered, v.Name)
+		}
+	}
+
+	var err error
+	if level.ReadRate, err = s.prom.ScalarOrNaN(ctx, metrics.ReadRateQuery(ns, name, nil, window), end); err != nil {
+		return level, err
+	}
+	if level.AckRate, err = s.prom.ScalarOrNaN(ctx, metrics.AckRateQuery(ns, name, nil, window), end); err != nil {
+		return level, err
+	}
+	if level.P99, err = s.prom.ScalarOrNaN(ctx, metrics.LatencyQuery(ns, name, 0.99, window), end); err != nil {
+		return level, err
+	}
+
+	pendingBefore, err := s.prom.ScalarOrNaN(ctx, metrics.PendingQuery(ns, name, buffered), start)
+	if err != nil {
+		return level, err
+	}
+	pendingAfter, err := s.prom.ScalarOrNaN(ctx, metrics.PendingQuery(ns, name, buffered), end)
+	if err != nil {
+		return level, err
+	}
+	level.PendingGrowth = (pendingAfter - pendingBefore) / window.Seconds()
+
+	switch {
+	case !math.IsNaN(level.PendingGrowth) && level.PendingGrowth > s.opts.MaxPendingGrowth:
+		level.Sustained, level.Limit = false, LimitPendingGrowth
+	case level.ReadRate > 0 && level.AckRate/level.ReadRate < s.opts.MinAckRatio:
+		level.Sustained, level.Limit = false, LimitAckRate
+	case s.opts.MaxP99 > 0 && !math.IsNaN(level.P99) && level.P99 > s.opts.MaxP99:
+		level.Sustained, level.Limit = false, LimitLatency
+	}
+
+	return level, nil
+}
+
+// WriteCapacityTable prints every rate tried during a capacity search followed by the outcome
+func WriteCapacityTable(out io.Writer, result *CapacityResult) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join([]string{"rpu", "sustained", "limit", "read (msg/s)", "ack (msg/s)", "pending growth (msg/s)", "p99 (ms)"}, "\t"))
+	for _, l := range result.Levels {
+		fmt.Fprintf(w, "%d\t%t\t%s\t%s\t%s\t%s\t%s\n", l.RPU, l.Sustained, l.Limit,
+			formatMetric(l.ReadRate, 1), formatMetric(l.AckRate, 1), formatMetric(l.PendingGrowth, 2), formatMetric(l.P99, 2))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if result.LimitingMetric == "" {
+		_, err := fmt.Fprintf(out, "\nmax sustained rpu: %d (upper bound of the search, no limit reached)\n", result.MaxSustainedRPU)
+		return err
+	}
+
+	_, err := fmt.Fprintf(out, "\nmax sustained rpu: %d, limited by %s\n", result.MaxSustainedRPU, result.LimitingMetric)
+	return err
+}
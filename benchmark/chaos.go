@@ -0,0 +1,394 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"github.com/ayildirim21/numaflow-perfman/metrics"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+// Faults the chaos scheduler can inject
+const (
+	FaultVertexPod         = "vertex-pod"
+	FaultISBPod            = "isb-pod"
+	FaultControllerRestart = "controller-restart"
+)
+
+// recoveryPollInterval is how often throughput is checked while waiting for a pipeline to recover
+const recoveryPollInterval = 5 * time.Second
+
+// ChaosPlan describes the faults injected into a pipeline during the measurement window of a run
+type ChaosPlan struct {
+	// Faults are injected at fixed offsets from the start of the measurement window
+	Faults []Fault `json:"faults"`
+	// Random, if set, injects faults at random intervals as well
+	Random *RandomFaults `json:"random,omitempty"`
+	// ISBService is the inter-step buffer service whose pods isb-pod faults delete
+	ISBService string `json:"isbService"`
+	// Baseline is the window throughput is averaged over right before a fault
+	Baseline Duration `json:"baseline"`
+	// RecoveryWindow is the window throughput is averaged over while waiting for recovery
+	RecoveryWindow Duration `json:"recoveryWindow"`
+	// Tolerance is the fraction below the baseline throughput may stay at and still count as recovered
+	Tolerance float64 `json:"tolerance"`
+	// RecoveryTimeout is how long to wait for recovery before giving up on a fault
+	RecoveryTimeout Duration `json:"recoveryTimeout"`
+}
+
+// Fault is a single fault injected at a fixed time
+type Fault struct {
+	// Action is one of vertex-pod, isb-pod or controller-restart
+	Action string `json:"action"`
+	// Vertex narrows vertex-pod faults to the pods of one vertex, by default any vertex pod is picked
+	Vertex string `json:"vertex,omitempty"`
+	// At is the offset from the start of the measurement window
+	At Duration `json:"at"`
+}
+
+// RandomFaults injects one of Actions, picked at random, after a random wait between MinInterval and MaxInterval
+type RandomFaults struct {
+	Actions     []string `json:"actions"`
+	Vertex      string   `json:"vertex,omitempty"`
+	MinInterval Duration `json:"minInterval"`
+	MaxInterval Duration `json:"maxInterval"`
+	// Seed makes the random schedule repeatable, zero seeds from the clock
+	Seed int64 `json:"seed"`
+}
+
+// ReadChaosPlan reads a chaos plan from a yaml file
+func ReadChaosPlan(path string) (*ChaosPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chaos plan: %w", err)
+	}
+
+	p := ChaosPlan{
+		ISBService:      util.ISBServiceName,
+		Baseline:        Duration{time.Minute},
+		RecoveryWindow:  Duration{30 * time.Second},
+		Tolerance:       0.1,
+		RecoveryTimeout: Duration{5 * time.Minute},
+	}
+	if err := yaml.UnmarshalStrict(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse chaos plan %s: %w", path, err)
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid chaos plan %s: %w", path, err)
+	}
+
+	return &p, nil
+}
+
+func (p *ChaosPlan) validate() error {
+	if len(p.Faults) == 0 && p.Random == nil {
+		return fmt.Errorf("plan needs at least one fault or random faults")
+	}
+	for i, f := range p.Faults {
+		if err := validateFaultAction(f.Action); err != nil {
+			return fmt.Errorf("faults[%d]: %w", i, err)
+		}
+		if f.At.Duration < 0 {
+			return fmt.Errorf("faults[%d]: at must not be negative", i)
+		}
+	}
+
+	if p.Random != nil {
+		if len(p.Random.Actions) == 0 {
+			return fmt.Errorf("random faults need at least one action")
+		}
+		for _, action := range p.Random.Actions {
+			if err := validateFaultAction(action); err != nil {
+				return fmt.Errorf("random: %w", err)
+			}
+		}
+		if p.Random.MinInterval.Duration <= 0 || p.Random.MaxInterval.Duration < p.Random.MinInterval.Duration {
+			return fmt.Errorf("random faults need a positive minInterval no larger than maxInterval")
+		}
+	}
+
+	if p.Baseline.Duration <= 0 || p.RecoveryWindow.Duration <= 0 || p.RecoveryTimeout.Duration <= 0 {
+		return fmt.Errorf("baseline, recoveryWindow and recoveryTimeout must be positive")
+	}
+	if p.Tolerance < 0 || p.Tolerance >= 1 {
+		return fmt.Errorf("tolerance must be in [0, 1)")
+	}
+
+	return nil
+}
+
+func validateFaultAction(action string) error {
+	switch action {
+	case FaultVertexPod, FaultISBPod, FaultControllerRestart:
+		return nil
+	default:
+		return fmt.Errorf("action must be one of %s, %s or %s", FaultVertexPod, FaultISBPod, FaultControllerRestart)
+	}
+}
+
+// FaultResult records a fault that was injected and how the pipeline recovered from it
+type FaultResult struct {
+	Time   time.Time
+	Action string
+	Target string
+	// Baseline is the sink throughput, in messages per second, right before the fault
+	Baseline float64
+	// Recovered is false if throughput did not return within tolerance of the baseline before the timeout
+	Recovered    bool
+	RecoveryTime time.Duration
+}
+
+// ChaosScheduler is an Observer injecting the faults of a plan and measuring recovery from each
+type ChaosScheduler struct {
+	KubeClient *kubernetes.Clientset
+	Prom       *metrics.PrometheusClient
+	Namespace  string
+	Plan       *ChaosPlan
+	Log        *zap.Logger
+	sinks      []string
+	rand       *rand.Rand
+
+	mu      sync.Mutex
+	results []FaultResult
+}
+
+// NewChaosScheduler returns a scheduler for the plan, measuring throughput at the sinks of obj
+func NewChaosScheduler(kubeClient *kubernetes.Clientset, prom *metrics.PrometheusClient, namespace string, obj *unstructured.Unstructured, plan *ChaosPlan, log *zap.Logger) (*ChaosScheduler, error) {
+	g, err := pipeline.ParseGraph(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := time.Now().UnixNano()
+	if plan.Random != nil && plan.Random.Seed != 0 {
+		seed = plan.Random.Seed
+	}
+
+	return &ChaosScheduler{
+		KubeClient: kubeClient,
+		Prom:       prom,
+		Namespace:  namespace,
+		Plan:       plan,
+		Log:        log,
+		sinks:      g.VerticesOfKind(pipeline.VertexSink),
+		rand:       rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+// Observe injects faults from the start of the measurement window until it closes, waiting for the
+// pipeline to recover from each fault before injecting the next. A fault due while the pipeline is
+// still recovering is injected as soon as it has.
+func (c *ChaosScheduler) Observe(ctx context.Context, rec *RunRecord) error {
+	start := time.Now()
+
+	fixed := append([]Fault(nil), c.Plan.Faults...)
+	sort.SliceStable(fixed, func(i, j int) bool { return fixed[i].At.Duration < fixed[j].At.Duration })
+
+	var random *Fault
+	if c.Plan.Random != nil {
+		random = c.nextRandomFault(0)
+	}
+
+	for len(fixed) > 0 || random != nil {
+		var fault Fault
+		if random == nil || (len(fixed) > 0 && fixed[0].At.Duration <= random.At.Duration) {
+			fault, fixed = fixed[0], fixed[1:]
+		} else {
+			fault = *random
+			random = c.nextRandomFault(fault.At.Duration)
+		}
+
+		if err := sleep(ctx, time.Until(start.Add(fault.At.Duration))); err != nil {
+			return err
+		}
+
+		if err := c.inject(ctx, rec, fault); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// One fault failing to inject, e.g. no pod to delete, should not end the run
+			c.Log.Warn("Unable to inject fault", zap.String("action", fault.Action), zap.Error(err))
+		}
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// nextRandomFault picks the random fault following one injected at offset after
+func (c *ChaosScheduler) nextRandomFault(after time.Duration) *Fault {
+	r := c.Plan.Random
+	wait := r.MinInterval.Duration
+	if spread := r.MaxInterval.Duration - r.MinInterval.Duration; spread > 0 {
+		wait += time.Duration(c.rand.Int63n(int64(spread)))
+	}
+
+	return &Fault{
+		Action: r.Actions[c.rand.Intn(len(r.Actions))],
+		Vertex: r.Vertex,
+		At:     Duration{after + wait},
+	}
+}
+
+// inject measures the baseline, injects a single fault and waits for the pipeline to recover from it
+func (c *ChaosScheduler) inject(ctx context.Context, rec *RunRecord, fault Fault) error {
+	baseline, err := c.throughput(ctx, rec.Pipeline, c.Plan.Baseline.Duration, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var target string
+	switch fault.Action {
+	case FaultVertexPod:
+		selector := fmt.Sprintf("%s=%s,%s=%s", util.PipelineNameLabel, rec.Pipeline, util.ComponentLabel, util.VertexComponent)
+		if fault.Vertex != "" {
+			selector += fmt.Sprintf(",%s=%s", util.VertexNameLabel, fault.Vertex)
+		}
+		target, err = c.deleteRandomPod(ctx, c.Namespace, selector)
+	case FaultISBPod:
+		target, err = c.deleteRandomPod(ctx, c.Namespace, fmt.Sprintf("%s=%s", util.ISBServiceNameLabel, c.Plan.ISBService))
+	case FaultControllerRestart:
+		target, err = c.restartController(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	result := FaultResult{Time: time.Now(), Action: fault.Action, Target: target, Baseline: baseline}
+	rec.AddEvent(Event{Time: result.Time, Type: "chaos", Target: target, Detail: fault.Action})
+	c.Log.Info("Injected fault", zap.String("pipeline", rec.Pipeline), zap.String("action", fault.Action),
+		zap.String("target", target), zap.Float64("baseline", baseline))
+
+	result.Recovered, result.RecoveryTime, err = c.waitForRecovery(ctx, rec.Pipeline, result.Time, baseline)
+	c.mu.Lock()
+	c.results = append(c.results, result)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	detail := fmt.Sprintf("%s recovered after %s", fault.Action, result.RecoveryTime.Round(time.Second))
+	if !result.Recovered {
+		detail = fmt.Sprintf("%s not recovered within %s", fault.Action, c.Plan.RecoveryTimeout.Duration)
+	}
+	rec.AddEvent(Event{Time: time.Now(), Type: "recovery", Target: target, Detail: detail})
+	c.Log.Info("Fault recovery", zap.String("pipeline", rec.Pipeline), zap.String("action", fault.Action),
+		zap.Bool("recovered", result.Recovered), zap.Duration("recovery-time", result.RecoveryTime))
+
+	return nil
+}
+
+// waitForRecovery polls throughput until a window lying entirely after the fault averages within tolerance
+// of the baseline. The recovery time is measured from the fault to the start of that window.
+// Without a baseline, e.g. when the sinks had not read anything yet, any throughput counts as recovered.
+func (c *ChaosScheduler) waitForRecovery(ctx context.Context, pipelineName string, faultAt time.Time, baseline float64) (bool, time.Duration, error) {
+	window := c.Plan.RecoveryWindow.Duration
+	deadline := faultAt.Add(c.Plan.RecoveryTimeout.Duration)
+	target := baseline * (1 - c.Plan.Tolerance)
+	if math.IsNaN(target) {
+		target = 0
+	}
+
+	if err := sleep(ctx, time.Until(faultAt.Add(window))); err != nil {
+		return false, 0, err
+	}
+
+	for {
+		now := time.Now()
+		rate, err := c.throughput(ctx, pipelineName, window, now)
+		if err != nil {
+			return false, 0, err
+		}
+		if !math.IsNaN(rate) && rate >= target {
+			return true, now.Add(-window).Sub(faultAt), nil
+		}
+
+		if now.After(deadline) {
+			return false, now.Sub(faultAt), nil
+		}
+
+		if err := sleep(ctx, recoveryPollInterval); err != nil {
+			return false, 0, err
+		}
+	}
+}
+
+// throughput is the rate at which the sinks read messages, averaged over window
+func (c *ChaosScheduler) throughput(ctx context.Context, pipelineName string, window time.Duration, at time.Time) (float64, error) {
+	return c.Prom.ScalarOrNaN(ctx, metrics.ReadRateQuery(c.Namespace, pipelineName, c.sinks, window), at)
+}
+
+func (c *ChaosScheduler) deleteRandomPod(ctx context.Context, namespace string, selector string) (string, error) {
+	pods, err := c.KubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return "", fmt.Errorf("failed to list pods %s: %w", selector, err)
+	}
+
+	var names []string
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp == nil {
+			names = append(names, pod.Name)
+		}
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no running pods match %s", selector)
+	}
+
+	name := names[c.rand.Intn(len(names))]
+	if err := c.KubeClient.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return "", fmt.Errorf("failed to delete pod %s: %w", name, err)
+	}
+
+	return name, nil
+}
+
+// restartController triggers a rolling restart of the numaflow controller the way kubectl rollout restart does
+func (c *ChaosScheduler) restartController(ctx context.Context) (string, error) {
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":"%s"}}}}}`,
+		time.Now().Format(time.RFC3339))
+	_, err := c.KubeClient.AppsV1().Deployments(util.NumaflowNamespace).Patch(ctx, util.NumaflowControllerName,
+		types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to restart numaflow controller: %w", err)
+	}
+
+	return util.NumaflowControllerName, nil
+}
+
+// Results returns the faults injected so far
+func (c *ChaosScheduler) Results() []FaultResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]FaultResult(nil), c.results...)
+}
+
+// WriteChaosTable prints every injected fault with the recovery it was followed by
+func WriteChaosTable(out io.Writer, results []FaultResult) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "time\taction\ttarget\tbaseline (msg/s)\trecovered\trecovery time")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\n", r.Time.Format(time.RFC3339), r.Action, r.Target,
+			formatMetric(r.Baseline, 1), r.Recovered, r.RecoveryTime.Round(time.Second))
+	}
+
+	return w.Flush()
+}
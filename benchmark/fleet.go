@@ -0,0 +1,325 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/ayildirim21/numaflow-perfman/metrics"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+// fleetPollInterval is how often the copies of a fleet are listed while waiting for them to run or be removed
+const fleetPollInterval = 2 * time.Second
+
+// FleetOptions configures a load test of many concurrent copies of one pipeline
+type FleetOptions struct {
+	// Copies lists the fleet sizes to run, one level each, e.g. 1, 10, 100
+	Copies []int
+	// StartTimeout bounds how long all copies of a level may take to reach Running
+	StartTimeout time.Duration
+	// Warmup, Duration and Cooldown are as for a single run; Cooldown separates levels
+	Warmup   time.Duration
+	Duration time.Duration
+	Cooldown time.Duration
+}
+
+// FleetLevel is the outcome of running one fleet size
+type FleetLevel struct {
+	Copies int `json:"copies"`
+	// Running is the number of copies that reached Running before the start timeout
+	Running int `json:"running"`
+	// StartP50, StartP95 and StartMax are the times from creating a copy until it was seen Running
+	StartP50 time.Duration `json:"startP50"`
+	StartP95 time.Duration `json:"startP95"`
+	StartMax time.Duration `json:"startMax"`
+	// ReconcileP99 is the p99 pipeline reconcile time of the controller while the fleet started, in milliseconds
+	ReconcileP99 Metric `json:"reconcileP99"`
+	// Aggregate is the total sink throughput of all copies, in messages per second
+	Aggregate Metric `json:"aggregate"`
+	// MinCopy and MaxCopy are the sink throughputs of the slowest and fastest copy
+	MinCopy Metric `json:"minCopy"`
+	MaxCopy Metric `json:"maxCopy"`
+	// Fairness is Jain's fairness index of the copies' throughputs, 1 when all copies got the same share
+	Fairness    Metric             `json:"fairness"`
+	Throughputs map[string]float64 `json:"throughputs"`
+	WindowStart time.Time          `json:"windowStart"`
+	WindowEnd   time.Time          `json:"windowEnd"`
+	Err         string             `json:"error,omitempty"`
+}
+
+// RunFleet runs every fleet size of opts in turn. Each level creates that many copies of obj, named
+// <name>-c<i>, waits until they run, measures them through the warmup and measurement window, then deletes them.
+// A failed level is recorded in its result and does not stop the test, unless ctx is cancelled.
+func (r *Runner) RunFleet(ctx context.Context, obj *unstructured.Unstructured, prom *metrics.PrometheusClient, opts FleetOptions) []FleetLevel {
+	levels := make([]FleetLevel, 0, len(opts.Copies))
+
+	for i, copies := range opts.Copies {
+		if i > 0 && opts.Cooldown > 0 {
+			r.Log.Info("Cooling down", zap.Duration("cooldown", opts.Cooldown))
+			if err := sleep(ctx, opts.Cooldown); err != nil {
+				break
+			}
+		}
+
+		r.Log.Info("Starting fleet level", zap.String("pipeline", obj.GetName()), zap.Int("copies", copies))
+		level, err := r.runFleetLevel(ctx, obj, copies, prom, opts)
+		if err != nil {
+			level.Err = err.Error()
+			r.Log.Error("Fleet level failed", zap.Int("copies", copies), zap.Error(err))
+		}
+		levels = append(levels, level)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return levels
+}
+
+func (r *Runner) runFleetLevel(ctx context.Context, obj *unstructured.Unstructured, copies int, prom *metrics.PrometheusClient, opts FleetOptions) (level FleetLevel, err error) {
+	nan := Metric(math.NaN())
+	level = FleetLevel{Copies: copies, ReconcileP99: nan, Aggregate: nan, MinCopy: nan, MaxCopy: nan, Fairness: nan}
+
+	g, err := pipeline.ParseGraph(obj)
+	if err != nil {
+		return level, err
+	}
+
+	resource := r.DynamicClient.Resource(pipeline.GVR).Namespace(r.Namespace)
+	createdAt := make(map[string]time.Time, copies)
+	defer func() {
+		if teardownErr := r.teardownFleet(createdAt); teardownErr != nil && err == nil {
+			err = teardownErr
+		}
+	}()
+
+	firstCreate := time.Now()
+	for i := 1; i <= copies; i++ {
+		instance := obj.DeepCopy()
+		instance.SetName(fmt.Sprintf("%s-c%d", obj.GetName(), i))
+		if _, err := resource.Create(ctx, instance, metav1.CreateOptions{}); err != nil {
+			return level, fmt.Errorf("failed to create pipeline %s: %w", instance.GetName(), err)
+		}
+		createdAt[instance.GetName()] = time.Now()
+	}
+	r.Log.Info("Created fleet", zap.String("pipeline", obj.GetName()), zap.Int("copies", copies), zap.Duration("took", time.Since(firstCreate)))
+
+	startTimes, err := r.waitForFleet(ctx, createdAt, opts.StartTimeout)
+	level.Running = len(startTimes)
+	if len(startTimes) > 0 {
+		level.StartP50 = time.Duration(metrics.Percentile(startTimes, 50))
+		level.StartP95 = time.Duration(metrics.Percentile(startTimes, 95))
+		level.StartMax = time.Duration(metrics.Percentile(startTimes, 100))
+	}
+	if err != nil {
+		return level, err
+	}
+	allRunning := time.Now()
+
+	reconcileP99, err := prom.ScalarOrNaN(ctx, metrics.ReconcileLatencyQuery(0.99, allRunning.Sub(firstCreate)), allRunning)
+	if err != nil {
+		return level, err
+	}
+	level.ReconcileP99 = Metric(reconcileP99)
+
+	r.Log.Info("Fleet running, warming up", zap.Int("copies", copies), zap.Duration("start-p95", level.StartP95), zap.Duration("warmup", opts.Warmup))
+	if err := sleep(ctx, opts.Warmup); err != nil {
+		return level, err
+	}
+	level.WindowStart = time.Now()
+	if err := sleep(ctx, opts.Duration); err != nil {
+		return level, err
+	}
+	level.WindowEnd = time.Now()
+
+	query := metrics.ReadRateByPipelineQuery(r.Namespace, regexp.QuoteMeta(obj.GetName())+"-c[0-9]+",
+		g.VerticesOfKind(pipeline.VertexSink), level.WindowEnd.Sub(level.WindowStart))
+	samples, err := prom.Query(ctx, query, level.WindowEnd)
+	if err != nil {
+		return level, err
+	}
+
+	level.Throughputs = make(map[string]float64, copies)
+	for _, s := range samples {
+		if _, ours := createdAt[s.Labels["pipeline"]]; ours {
+			level.Throughputs[s.Labels["pipeline"]] = s.Value
+		}
+	}
+	// Copies that wrote nothing to their sinks have no series, but count towards fairness
+	for name := range createdAt {
+		if _, found := level.Throughputs[name]; !found {
+			level.Throughputs[name] = 0
+		}
+	}
+	aggregate, minCopy, maxCopy, index := fairness(level.Throughputs)
+	level.Aggregate, level.MinCopy, level.MaxCopy, level.Fairness = Metric(aggregate), Metric(minCopy), Metric(maxCopy), Metric(index)
+
+	r.Log.Info("Measured fleet", zap.Int("copies", copies), zap.Float64("aggregate", aggregate), zap.Float64("fairness", index))
+	return level, nil
+}
+
+// waitForFleet polls the copies until all of them are Running, returning how long each that did took from its creation,
+// in nanoseconds. A single list per poll keeps the load on the API server independent of the fleet size.
+func (r *Runner) waitForFleet(ctx context.Context, createdAt map[string]time.Time, timeout time.Duration) ([]float64, error) {
+	runningAt := make(map[string]time.Time, len(createdAt))
+
+	pollCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := wait.PollUntilContextCancel(pollCtx, fleetPollInterval, true, func(ctx context.Context) (bool, error) {
+		list, err := r.DynamicClient.Resource(pipeline.GVR).Namespace(r.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		now := time.Now()
+		for _, item := range list.Items {
+			if _, ours := createdAt[item.GetName()]; !ours {
+				continue
+			}
+			if _, seen := runningAt[item.GetName()]; seen {
+				continue
+			}
+			if phase, _, _ := unstructured.NestedString(item.Object, "status", "phase"); phase == pipeline.PhaseRunning {
+				runningAt[item.GetName()] = now
+			}
+		}
+
+		return len(runningAt) == len(createdAt), nil
+	})
+
+	startTimes := make([]float64, 0, len(runningAt))
+	for name, at := range runningAt {
+		startTimes = append(startTimes, float64(at.Sub(createdAt[name])))
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return startTimes, ctx.Err()
+		}
+		return startTimes, fmt.Errorf("%d of %d pipelines running after %s: %w", len(runningAt), len(createdAt), timeout, err)
+	}
+
+	return startTimes, nil
+}
+
+// teardownFleet deletes every copy and waits until the copies and their pods are gone
+func (r *Runner) teardownFleet(createdAt map[string]time.Time) error {
+	if len(createdAt) == 0 {
+		return nil
+	}
+
+	// The run context may already be cancelled, tear down with a context of our own
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	start := time.Now()
+	resource := r.DynamicClient.Resource(pipeline.GVR).Namespace(r.Namespace)
+	for name := range createdAt {
+		if err := resource.Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete pipeline %s: %w", name, err)
+		}
+	}
+
+	err := wait.PollUntilContextCancel(ctx, fleetPollInterval, true, func(ctx context.Context) (bool, error) {
+		list, err := resource.List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, item := range list.Items {
+			if _, ours := createdAt[item.GetName()]; ours {
+				return false, nil
+			}
+		}
+
+		pods, err := r.KubeClient.CoreV1().Pods(r.Namespace).List(ctx, metav1.ListOptions{LabelSelector: util.PipelineNameLabel})
+		if err != nil {
+			return false, err
+		}
+		for _, pod := range pods.Items {
+			if _, ours := createdAt[pod.Labels[util.PipelineNameLabel]]; ours {
+				return false, nil
+			}
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("fleet of %d pipelines was not removed: %w", len(createdAt), err)
+	}
+
+	r.Log.Info("Deleted fleet", zap.Int("copies", len(createdAt)), zap.Duration("took", time.Since(start)))
+	return nil
+}
+
+// fairness returns the sum, minimum and maximum of the throughputs and Jain's fairness index of them
+func fairness(throughputs map[string]float64) (sum float64, minimum float64, maximum float64, index float64) {
+	if len(throughputs) == 0 {
+		return math.NaN(), math.NaN(), math.NaN(), math.NaN()
+	}
+
+	var sumOfSquares float64
+	minimum, maximum = math.Inf(1), math.Inf(-1)
+	for _, t := range throughputs {
+		sum += t
+		sumOfSquares += t * t
+		minimum = math.Min(minimum, t)
+		maximum = math.Max(maximum, t)
+	}
+
+	index = math.NaN()
+	if sumOfSquares > 0 {
+		index = sum * sum / (float64(len(throughputs)) * sumOfSquares)
+	}
+
+	return sum, minimum, maximum, index
+}
+
+// SaveFleetLevels writes the levels of a fleet test as json into dir, returning the path of the file written
+func SaveFleetLevels(dir string, pipelineName string, levels []FleetLevel) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create fleet result directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(levels, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal fleet results: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("fleet-%s-%s.json", pipelineName, time.Now().UTC().Format("20060102-150405")))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write fleet results: %w", err)
+	}
+
+	return path, nil
+}
+
+// WriteFleetTable prints one row per fleet size
+func WriteFleetTable(out io.Writer, levels []FleetLevel) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "copies\trunning\tstart p50 (s)\tstart p95 (s)\tstart max (s)\treconcile p99 (ms)\taggregate (msg/s)\tmin copy (msg/s)\tmax copy (msg/s)\tfairness\terror")
+	for _, l := range levels {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", l.Copies, l.Running,
+			formatMetric(l.StartP50.Seconds(), 1), formatMetric(l.StartP95.Seconds(), 1), formatMetric(l.StartMax.Seconds(), 1),
+			formatMetric(float64(l.ReconcileP99), 1), formatMetric(float64(l.Aggregate), 1), formatMetric(float64(l.MinCopy), 1),
+			formatMetric(float64(l.MaxCopy), 1), formatMetric(float64(l.Fairness), 3), l.Err)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,248 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+)
+
+const (
+	ShapeRamp  = "ramp"
+	ShapeSteps = "steps"
+	ShapeSpike = "spike"
+	ShapeSine  = "sine"
+
+	LoadModeRPU      = "rpu"
+	LoadModeReplicas = "replicas"
+)
+
+// LoadProfile describes time-varying load applied to a pipeline's generator source
+type LoadProfile struct {
+	// Vertex is the generator source to drive, by default the pipeline's only generator
+	Vertex string `json:"vertex"`
+	// Mode is either rpu, to patch the generator rate, or replicas, to scale the source
+	Mode string `json:"mode"`
+	// Interval is how often the load is recomputed and applied
+	Interval Duration `json:"interval"`
+	// Shape selects which of the shape settings below applies
+	Shape string `json:"shape"`
+
+	Ramp  *RampShape  `json:"ramp,omitempty"`
+	Steps []LoadStep  `json:"steps,omitempty"`
+	Spike *SpikeShape `json:"spike,omitempty"`
+	Sine  *SineShape  `json:"sine,omitempty"`
+}
+
+// RampShape changes load linearly from From to To over Over, then holds To
+type RampShape struct {
+	From int64    `json:"from"`
+	To   int64    `json:"to"`
+	Over Duration `json:"over"`
+}
+
+// LoadStep holds Value for Hold; the last step is held until the run ends
+type LoadStep struct {
+	Value int64    `json:"value"`
+	Hold  Duration `json:"hold"`
+}
+
+// SpikeShape holds Base, jumping to Peak for Hold once every Every
+type SpikeShape struct {
+	Base  int64    `json:"base"`
+	Peak  int64    `json:"peak"`
+	Every Duration `json:"every"`
+	Hold  Duration `json:"hold"`
+}
+
+// SineShape oscillates between Min and Max with the given Period, starting at Min
+type SineShape struct {
+	Min    int64    `json:"min"`
+	Max    int64    `json:"max"`
+	Period Duration `json:"period"`
+}
+
+// ReadLoadProfile reads a load profile from a yaml file
+func ReadLoadProfile(path string) (*LoadProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read load profile: %w", err)
+	}
+
+	p := LoadProfile{Mode: LoadModeRPU, Interval: Duration{10 * time.Second}}
+	if err := yaml.UnmarshalStrict(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse load profile %s: %w", path, err)
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid load profile %s: %w", path, err)
+	}
+
+	return &p, nil
+}
+
+func (p *LoadProfile) validate() error {
+	if p.Mode != LoadModeRPU && p.Mode != LoadModeReplicas {
+		return fmt.Errorf("mode must be %s or %s", LoadModeRPU, LoadModeReplicas)
+	}
+	if p.Interval.Duration <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+
+	switch p.Shape {
+	case ShapeRamp:
+		if p.Ramp == nil || p.Ramp.Over.Duration <= 0 {
+			return fmt.Errorf("ramp shape needs ramp.from, ramp.to and a positive ramp.over")
+		}
+	case ShapeSteps:
+		if len(p.Steps) == 0 {
+			return fmt.Errorf("steps shape needs at least one step")
+		}
+	case ShapeSpike:
+		if p.Spike == nil || p.Spike.Every.Duration <= 0 || p.Spike.Hold.Duration <= 0 {
+			return fmt.Errorf("spike shape needs spike.base, spike.peak and positive spike.every and spike.hold")
+		}
+	case ShapeSine:
+		if p.Sine == nil || p.Sine.Period.Duration <= 0 {
+			return fmt.Errorf("sine shape needs sine.min, sine.max and a positive sine.period")
+		}
+	default:
+		return fmt.Errorf("shape must be one of %s, %s, %s or %s", ShapeRamp, ShapeSteps, ShapeSpike, ShapeSine)
+	}
+
+	return nil
+}
+
+// ValueAt returns the load the profile prescribes once elapsed has passed since it started
+func (p *LoadProfile) ValueAt(elapsed time.Duration) int64 {
+	switch p.Shape {
+	case ShapeRamp:
+		if elapsed >= p.Ramp.Over.Duration {
+			return p.Ramp.To
+		}
+		progress := float64(elapsed) / float64(p.Ramp.Over.Duration)
+		return p.Ramp.From + int64(math.Round(progress*float64(p.Ramp.To-p.Ramp.From)))
+	case ShapeSteps:
+		for _, step := range p.Steps {
+			if elapsed < step.Hold.Duration {
+				return step.Value
+			}
+			elapsed -= step.Hold.Duration
+		}
+		return p.Steps[len(p.Steps)-1].Value
+	case ShapeSpike:
+		if elapsed%p.Spike.Every.Duration < p.Spike.Hold.Duration && elapsed >= p.Spike.Every.Duration {
+			return p.Spike.Peak
+		}
+		return p.Spike.Base
+	case ShapeSine:
+		phase := 2 * math.Pi * float64(elapsed) / float64(p.Sine.Period.Duration)
+		amplitude := float64(p.Sine.Max-p.Sine.Min) / 2
+		return p.Sine.Min + int64(math.Round(amplitude-amplitude*math.Cos(phase)))
+	}
+
+	return 0
+}
+
+// resolveGenerator checks that vertex is a generator source of the pipeline, or picks the only generator if vertex is empty
+func resolveGenerator(g *pipeline.Graph, vertex string) (string, error) {
+	generators := g.Generators()
+	if vertex == "" {
+		if len(generators) != 1 {
+			return "", fmt.Errorf("generator vertex must be named, pipeline %s has %d generator sources", g.Name, len(generators))
+		}
+		return generators[0], nil
+	}
+
+	for _, generator := range generators {
+		if generator == vertex {
+			return vertex, nil
+		}
+	}
+
+	return "", fmt.Errorf("pipeline %s has no generator source %s", g.Name, vertex)
+}
+
+// LoadDriver applies a load profile to a running pipeline
+type LoadDriver struct {
+	DynamicClient *dynamic.DynamicClient
+	Namespace     string
+	Profile       *LoadProfile
+	Log           *zap.Logger
+	vertex        string
+}
+
+// NewLoadDriver returns a driver for the profile, resolving the generator vertex it drives in obj
+func NewLoadDriver(dynamicClient *dynamic.DynamicClient, namespace string, obj *unstructured.Unstructured, profile *LoadProfile, log *zap.Logger) (*LoadDriver, error) {
+	g, err := pipeline.ParseGraph(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	vertex, err := resolveGenerator(g, profile.Vertex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoadDriver{
+		DynamicClient: dynamicClient,
+		Namespace:     namespace,
+		Profile:       profile,
+		Log:           log,
+		vertex:        vertex,
+	}, nil
+}
+
+// Observe is an Observer applying the profile from the start of the measurement window until it closes,
+// recording every change of load as an event
+func (d *LoadDriver) Observe(ctx context.Context, rec *RunRecord) error {
+	start := time.Now()
+	ticker := time.NewTicker(d.Profile.Interval.Duration)
+	defer ticker.Stop()
+
+	last := int64(-1)
+	for {
+		value := d.Profile.ValueAt(time.Since(start))
+		if value != last {
+			if err := d.apply(ctx, rec.Pipeline, value); err != nil {
+				return err
+			}
+
+			detail := fmt.Sprintf("%s set to %d", d.Profile.Mode, value)
+			if last >= 0 {
+				detail = fmt.Sprintf("%s changed from %d to %d", d.Profile.Mode, last, value)
+			}
+			rec.AddEvent(Event{Time: time.Now(), Type: "load", Target: d.vertex, Detail: detail})
+			d.Log.Info("Load changed", zap.String("pipeline", rec.Pipeline), zap.String("vertex", d.vertex),
+				zap.String("mode", d.Profile.Mode), zap.Int64("value", value))
+			last = value
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *LoadDriver) apply(ctx context.Context, pipelineName string, value int64) error {
+	mutation := pipeline.SetGeneratorRPU(value)
+	if d.Profile.Mode == LoadModeReplicas {
+		mutation = pipeline.SetReplicas(value)
+	}
+
+	if err := pipeline.UpdateVertex(ctx, d.DynamicClient, d.Namespace, pipelineName, d.vertex, mutation); err != nil {
+		return fmt.Errorf("failed to apply load to vertex %s: %w", d.vertex, err)
+	}
+
+	return nil
+}
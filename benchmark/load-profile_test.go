@@ -0,0 +1,55 @@
+package benchmark
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadProfileValueAt(t *testing.T) {
+	ramp := &LoadProfile{Shape: ShapeRamp, Ramp: &RampShape{From: 100, To: 200, Over: Duration{10 * time.Second}}}
+	rampDown := &LoadProfile{Shape: ShapeRamp, Ramp: &RampShape{From: 200, To: 100, Over: Duration{10 * time.Second}}}
+	steps := &LoadProfile{Shape: ShapeSteps, Steps: []LoadStep{
+		{Value: 10, Hold: Duration{time.Minute}},
+		{Value: 20, Hold: Duration{2 * time.Minute}},
+		{Value: 30, Hold: Duration{time.Minute}},
+	}}
+	spike := &LoadProfile{Shape: ShapeSpike, Spike: &SpikeShape{Base: 5, Peak: 50, Every: Duration{time.Minute}, Hold: Duration{10 * time.Second}}}
+	sine := &LoadProfile{Shape: ShapeSine, Sine: &SineShape{Min: 10, Max: 30, Period: Duration{4 * time.Minute}}}
+
+	tests := []struct {
+		name    string
+		profile *LoadProfile
+		elapsed time.Duration
+		want    int64
+	}{
+		{name: "ramp start", profile: ramp, elapsed: 0, want: 100},
+		{name: "ramp midway", profile: ramp, elapsed: 5 * time.Second, want: 150},
+		{name: "ramp end", profile: ramp, elapsed: 10 * time.Second, want: 200},
+		{name: "ramp holds after end", profile: ramp, elapsed: time.Hour, want: 200},
+		{name: "ramp down", profile: rampDown, elapsed: 2500 * time.Millisecond, want: 175},
+		{name: "first step", profile: steps, elapsed: 0, want: 10},
+		{name: "end of first step", profile: steps, elapsed: time.Minute - time.Nanosecond, want: 10},
+		{name: "second step", profile: steps, elapsed: time.Minute, want: 20},
+		{name: "end of second step", profile: steps, elapsed: 3*time.Minute - time.Nanosecond, want: 20},
+		{name: "last step", profile: steps, elapsed: 3 * time.Minute, want: 30},
+		{name: "last step holds", profile: steps, elapsed: time.Hour, want: 30},
+		{name: "no spike at start", profile: spike, elapsed: 0, want: 5},
+		{name: "base between spikes", profile: spike, elapsed: 30 * time.Second, want: 5},
+		{name: "spike starts", profile: spike, elapsed: time.Minute, want: 50},
+		{name: "spike holds", profile: spike, elapsed: time.Minute + 10*time.Second - time.Nanosecond, want: 50},
+		{name: "spike ends", profile: spike, elapsed: time.Minute + 10*time.Second, want: 5},
+		{name: "next spike", profile: spike, elapsed: 2 * time.Minute, want: 50},
+		{name: "sine starts at min", profile: sine, elapsed: 0, want: 10},
+		{name: "sine quarter period", profile: sine, elapsed: time.Minute, want: 20},
+		{name: "sine peaks at half period", profile: sine, elapsed: 2 * time.Minute, want: 30},
+		{name: "sine back at min", profile: sine, elapsed: 4 * time.Minute, want: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.profile.ValueAt(tt.elapsed); got != tt.want {
+				t.Errorf("ValueAt(%s) = %d, want %d", tt.elapsed, got, tt.want)
+			}
+		})
+	}
+}
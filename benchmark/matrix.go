@@ -0,0 +1,274 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/ayildirim21/numaflow-perfman/metrics"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+)
+
+// Matrix describes a parameter sweep over a templated pipeline
+type Matrix struct {
+	// Scenario is a built-in library scenario to sweep, File a pipeline template, relative to the spec; exactly one must be set
+	Scenario string `json:"scenario"`
+	File     string `json:"file"`
+	// Values are template values shared by every run
+	Values map[string]interface{} `json:"values"`
+	// Parameters maps each swept template value to the values it takes
+	Parameters map[string][]interface{} `json:"parameters"`
+
+	Warmup   Duration `json:"warmup"`
+	Duration Duration `json:"duration"`
+	Cooldown Duration `json:"cooldown"`
+}
+
+// Duration is a time.Duration that reads from strings such as "5m" in yaml
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return fmt.Errorf("duration must be a string such as \"5m\": %s", data)
+	}
+
+	d.Duration, err = time.ParseDuration(s)
+	return err
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// LoadMatrix reads a matrix spec from a yaml file
+func LoadMatrix(path string) (*Matrix, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read matrix spec: %w", err)
+	}
+
+	var m Matrix
+	if err := yaml.UnmarshalStrict(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse matrix spec %s: %w", path, err)
+	}
+
+	if (m.Scenario == "") == (m.File == "") {
+		return nil, fmt.Errorf("matrix spec %s must set exactly one of scenario and file", path)
+	}
+	if m.File != "" && !filepath.IsAbs(m.File) {
+		m.File = filepath.Join(filepath.Dir(path), m.File)
+	}
+	if m.Duration.Duration <= 0 {
+		return nil, fmt.Errorf("matrix spec %s must set a positive duration", path)
+	}
+	for name, values := range m.Parameters {
+		if len(values) == 0 {
+			return nil, fmt.Errorf("matrix parameter %s has no values", name)
+		}
+	}
+
+	return &m, nil
+}
+
+// ParameterNames returns the swept parameters in the order they appear in result tables
+func (m *Matrix) ParameterNames() []string {
+	names := make([]string, 0, len(m.Parameters))
+	for name := range m.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Combinations expands the matrix into the template values of every run, shared values included
+func (m *Matrix) Combinations() []map[string]string {
+	combinations := []map[string]string{{}}
+	for _, name := range m.ParameterNames() {
+		var expanded []map[string]string
+		for _, c := range combinations {
+			for _, v := range m.Parameters[name] {
+				next := make(map[string]string, len(c)+1)
+				for k, existing := range c {
+					next[k] = existing
+				}
+				next[name] = fmt.Sprint(v)
+				expanded = append(expanded, next)
+			}
+		}
+		combinations = expanded
+	}
+
+	for _, c := range combinations {
+		for k, v := range m.Values {
+			if _, swept := c[k]; !swept {
+				c[k] = fmt.Sprint(v)
+			}
+		}
+	}
+
+	return combinations
+}
+
+// PrepareFunc renders, checks and parses the pipeline for one combination of template values
+type PrepareFunc func(values map[string]string) (*unstructured.Unstructured, error)
+
+// MatrixResult is the outcome of one run of a matrix
+type MatrixResult struct {
+	Values  map[string]string
+	Record  *RunRecord
+	Summary *metrics.Summary
+	Err     error
+}
+
+// RunMatrix executes every combination of the matrix sequentially, with a cooldown between runs.
+// A failed run is recorded in its result and does not stop the sweep, unless ctx is cancelled.
+func (r *Runner) RunMatrix(ctx context.Context, m *Matrix, prepare PrepareFunc, prom *metrics.PrometheusClient) []MatrixResult {
+	combinations := m.Combinations()
+	results := make([]MatrixResult, 0, len(combinations))
+
+	for i, values := range combinations {
+		if i > 0 && m.Cooldown.Duration > 0 {
+			r.Log.Info("Cooling down", zap.Duration("cooldown", m.Cooldown.Duration))
+			if err := sleep(ctx, m.Cooldown.Duration); err != nil {
+				break
+			}
+		}
+
+		r.Log.Info("Starting matrix run", zap.Int("run", i+1), zap.Int("of", len(combinations)), zap.Any("values", values))
+		result := MatrixResult{Values: values}
+		result.Record, result.Summary, result.Err = r.runCombination(ctx, i, values, m, prepare, prom)
+		if result.Err != nil {
+			r.Log.Error("Matrix run failed", zap.Int("run", i+1), zap.Error(result.Err))
+		}
+		results = append(results, result)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return results
+}
+
+func (r *Runner) runCombination(ctx context.Context, index int, values map[string]string, m *Matrix, prepare PrepareFunc, prom *metrics.PrometheusClient) (*RunRecord, *metrics.Summary, error) {
+	obj, err := prepare(values)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj.SetName(fmt.Sprintf("%s-m%d", obj.GetName(), index+1))
+
+	g, err := pipeline.ParseGraph(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rec, runErr := r.Run(ctx, obj, RunOptions{Warmup: m.Warmup.Duration, Duration: m.Duration.Duration})
+	if rec == nil {
+		return nil, nil, runErr
+	}
+
+	// A run whose teardown failed still measured a full window, summarize it before reporting the failure
+	summary, err := metrics.Summarize(ctx, prom, r.Namespace, rec.Pipeline, g.VerticesOfKind(pipeline.VertexSink), rec.WindowStart, rec.WindowEnd)
+	if err != nil {
+		return rec, nil, fmt.Errorf("failed to collect metrics: %w", err)
+	}
+
+	return rec, summary, runErr
+}
+
+var summaryColumns = []string{"pipeline", "throughput (msg/s)", "p50 (ms)", "p95 (ms)", "p99 (ms)", "cpu (cores)", "memory (MiB)", "error"}
+
+func resultRow(parameters []string, result MatrixResult) []string {
+	row := make([]string, 0, len(parameters)+len(summaryColumns))
+	for _, p := range parameters {
+		row = append(row, result.Values[p])
+	}
+
+	pipelineName := ""
+	if result.Record != nil {
+		pipelineName = result.Record.Pipeline
+	}
+	row = append(row, pipelineName)
+
+	if s := result.Summary; s != nil {
+		row = append(row,
+			formatMetric(s.Throughput, 1),
+			formatMetric(s.LatencyP50, 2),
+			formatMetric(s.LatencyP95, 2),
+			formatMetric(s.LatencyP99, 2),
+			formatMetric(s.CPUCores, 3),
+			formatMetric(s.MemoryBytes/(1<<20), 1),
+		)
+	} else {
+		row = append(row, "-", "-", "-", "-", "-", "-")
+	}
+
+	errMsg := ""
+	if result.Err != nil {
+		errMsg = result.Err.Error()
+	}
+
+	return append(row, errMsg)
+}
+
+// WriteMatrixTable prints the results as an aligned table with a row per combination
+func WriteMatrixTable(out io.Writer, parameters []string, results []MatrixResult) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(append(append([]string{}, parameters...), summaryColumns...), "\t"))
+	for _, result := range results {
+		fmt.Fprintln(w, strings.Join(resultRow(parameters, result), "\t"))
+	}
+
+	return w.Flush()
+}
+
+// WriteMatrixCSV writes the results as csv with a row per combination
+func WriteMatrixCSV(path string, parameters []string, results []MatrixResult) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write(append(append([]string{}, parameters...), summaryColumns...)); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if err := w.Write(resultRow(parameters, result)); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
+
+func formatMetric(v float64, precision int) string {
+	if math.IsNaN(v) {
+		return "-"
+	}
+
+	return strconv.FormatFloat(v, 'f', precision, 64)
+}
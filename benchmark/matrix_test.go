@@ -0,0 +1,42 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMatrixFile(t *testing.T) {
+	dir := t.TempDir()
+	absolute := filepath.Join(dir, "elsewhere", "pipeline.yaml")
+
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{name: "next to the spec", file: "pipeline.yaml", want: filepath.Join(dir, "specs", "pipeline.yaml")},
+		{name: "relative to the spec", file: "../pipelines/pipeline.yaml", want: filepath.Join(dir, "pipelines", "pipeline.yaml")},
+		{name: "absolute", file: absolute, want: absolute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "specs", "matrix.yaml")
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte("file: "+tt.file+"\nduration: 5m\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			m, err := LoadMatrix(path)
+			if err != nil {
+				t.Fatalf("LoadMatrix() error = %v", err)
+			}
+			if m.File != tt.want {
+				t.Errorf("File = %q, want %q", m.File, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// Metric is a measured value that may be NaN when there was no data. It is written to json as null,
+// since json has no representation of NaN.
+type Metric float64
+
+func (m Metric) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(float64(m)) || math.IsInf(float64(m), 0) {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(float64(m))
+}
+
+func (m *Metric) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*m = Metric(math.NaN())
+		return nil
+	}
+
+	return json.Unmarshal(data, (*float64)(m))
+}
@@ -0,0 +1,80 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Event is something perfman did to a pipeline during a run, such as changing its load
+type Event struct {
+	Time   time.Time `json:"time"`
+	Type   string    `json:"type"`
+	Target string    `json:"target"`
+	Detail string    `json:"detail"`
+}
+
+// RunRecord describes a finished benchmark run, most importantly its measurement window
+type RunRecord struct {
+	Pipeline  string `json:"pipeline"`
+	Namespace string `json:"namespace"`
+	Warmup    string `json:"warmup"`
+	Duration  string `json:"duration"`
+
+	CreatedAt   time.Time `json:"createdAt"`
+	RunningAt   time.Time `json:"runningAt"`
+	WindowStart time.Time `json:"windowStart"`
+	WindowEnd   time.Time `json:"windowEnd"`
+	DeletedAt   time.Time `json:"deletedAt"`
+
+	Events []Event `json:"events,omitempty"`
+
+	mu sync.Mutex
+}
+
+// AddEvent records an event, it is safe to call from concurrent observers
+func (rec *RunRecord) AddEvent(e Event) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.Events = append(rec.Events, e)
+}
+
+// Save writes the record as json into dir, returning the path of the file written
+func (rec *RunRecord) Save(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create run record directory: %w", err)
+	}
+
+	rec.mu.Lock()
+	data, err := json.MarshalIndent(rec, "", "  ")
+	rec.mu.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal run record: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.json", rec.Pipeline, rec.CreatedAt.UTC().Format("20060102-150405")))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write run record: %w", err)
+	}
+
+	return path, nil
+}
+
+// LoadRunRecord reads a run record written by Save
+func LoadRunRecord(path string) (*RunRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run record: %w", err)
+	}
+
+	var rec RunRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to parse run record %s: %w", path, err)
+	}
+
+	return &rec, nil
+}
@@ -0,0 +1,161 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+)
+
+// cleanupTimeout bounds how long a run waits for its pipeline to be torn down
+const cleanupTimeout = 10 * time.Minute
+
+// Runner executes time-boxed benchmark runs on the cluster
+type Runner struct {
+	DynamicClient *dynamic.DynamicClient
+	KubeClient    *kubernetes.Clientset
+	Namespace     string
+	Log           *zap.Logger
+}
+
+// RunOptions configures a single benchmark run
+type RunOptions struct {
+	// Warmup is how long the pipeline runs before the measurement window opens
+	Warmup time.Duration
+	// Duration is the length of the measurement window
+	Duration time.Duration
+	// Observers run concurrently with the measurement window and are cancelled when it closes
+	Observers []Observer
+}
+
+// Observer acts on or watches a pipeline during the measurement window of a run.
+// It should return once ctx is cancelled; returning context.Canceled then is not a failure.
+type Observer func(ctx context.Context, rec *RunRecord) error
+
+// Start creates the pipeline and waits until it is running. Whenever the returned record is not nil
+// the pipeline was created, and the record must be passed to Teardown even if Start returned an error.
+func (r *Runner) Start(ctx context.Context, obj *unstructured.Unstructured) (*RunRecord, error) {
+	record := &RunRecord{
+		Pipeline:  obj.GetName(),
+		Namespace: r.Namespace,
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := r.DynamicClient.Resource(pipeline.GVR).Namespace(r.Namespace).Create(ctx, obj, metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to create pipeline %s: %w", obj.GetName(), err)
+	}
+	r.Log.Info("Created pipeline", zap.String("pipeline", record.Pipeline))
+
+	if err := pipeline.WaitForPhase(ctx, r.DynamicClient, r.Namespace, record.Pipeline, pipeline.PhaseRunning, r.Log); err != nil {
+		return record, err
+	}
+	record.RunningAt = time.Now()
+
+	return record, nil
+}
+
+// Teardown deletes the pipeline of a run and waits until its pods are gone
+func (r *Runner) Teardown(rec *RunRecord) error {
+	// The run context may already be cancelled, tear down with a context of our own
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	took, err := pipeline.Delete(ctx, r.DynamicClient, r.KubeClient, r.Namespace, rec.Pipeline, r.Log)
+	if err != nil {
+		return err
+	}
+
+	rec.DeletedAt = time.Now()
+	r.Log.Info("Deleted pipeline", zap.String("pipeline", rec.Pipeline), zap.Duration("took", took))
+	return nil
+}
+
+// Run creates the pipeline, waits for it to be running, keeps it running through the warmup and the
+// measurement window, then deletes it and waits for its pods to be gone.
+// The pipeline is deleted even if the run fails or ctx is cancelled once it has been created.
+// If only the teardown fails the measurement is complete, and the record is returned along with the error.
+func (r *Runner) Run(ctx context.Context, obj *unstructured.Unstructured, opts RunOptions) (rec *RunRecord, err error) {
+	record, err := r.Start(ctx, obj)
+	if record == nil {
+		return nil, err
+	}
+
+	defer func() {
+		if teardownErr := r.Teardown(record); teardownErr != nil && err == nil {
+			err = teardownErr
+		}
+	}()
+
+	if err != nil {
+		return nil, err
+	}
+
+	record.Warmup = opts.Warmup.String()
+	record.Duration = opts.Duration.String()
+	r.Log.Info("Pipeline running, warming up", zap.String("pipeline", record.Pipeline), zap.Duration("warmup", opts.Warmup))
+
+	if err := sleep(ctx, opts.Warmup); err != nil {
+		return nil, err
+	}
+
+	record.WindowStart = time.Now()
+	r.Log.Info("Measurement window opened", zap.String("pipeline", record.Pipeline), zap.Duration("duration", opts.Duration))
+
+	if err := r.measure(ctx, record, opts); err != nil {
+		return nil, err
+	}
+
+	record.WindowEnd = time.Now()
+	r.Log.Info("Measurement window closed", zap.String("pipeline", record.Pipeline))
+
+	return record, nil
+}
+
+// measure keeps the pipeline running for the measurement window while the observers run alongside it
+func (r *Runner) measure(ctx context.Context, rec *RunRecord, opts RunOptions) error {
+	windowCtx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	errCh := make(chan error, len(opts.Observers))
+	for _, observe := range opts.Observers {
+		go func(observe Observer) {
+			errCh <- observe(windowCtx, rec)
+		}(observe)
+	}
+
+	<-windowCtx.Done()
+
+	var firstErr error
+	for range opts.Observers {
+		if err := <-errCh; err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return firstErr
+}
+
+// sleep waits for d, returning early with an error if ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,226 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/ayildirim21/numaflow-perfman/metrics"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+// readyPollInterval is how often the pods of a vertex are listed while waiting for it to scale
+const readyPollInterval = 2 * time.Second
+
+// ScalingOptions configures a sweep of the replica count of one vertex
+type ScalingOptions struct {
+	// Vertex is the vertex whose replicas are pinned
+	Vertex string
+	// Replicas lists the replica counts to measure, in order
+	Replicas []int64
+	// ReadyTimeout bounds how long a replica count may take until all its pods are ready
+	ReadyTimeout time.Duration
+	// Settle is how long each replica count runs once its pods are ready before it is measured, Hold how long it is measured for
+	Settle time.Duration
+	Hold   time.Duration
+}
+
+// ScalingStep is the measurement of one replica count
+type ScalingStep struct {
+	Replicas int64 `json:"replicas"`
+	// VertexRate is the rate at which the swept vertex read messages, SinkRate the rate at which the sinks did
+	VertexRate Metric `json:"vertexRate"`
+	SinkRate   Metric `json:"sinkRate"`
+	// Speedup is VertexRate relative to that of one replica, Efficiency is Speedup per replica
+	Speedup    Metric `json:"speedup"`
+	Efficiency Metric `json:"efficiency"`
+}
+
+// ScalingResult is the outcome of a scaling sweep
+type ScalingResult struct {
+	Record *RunRecord    `json:"-"`
+	Vertex string        `json:"vertex"`
+	Steps  []ScalingStep `json:"steps"`
+}
+
+// ReplicaSteps returns 1, 2, 4, ... up to and including maximum
+func ReplicaSteps(maximum int64) []int64 {
+	var steps []int64
+	for n := int64(1); n < maximum; n *= 2 {
+		steps = append(steps, n)
+	}
+
+	return append(steps, maximum)
+}
+
+// SweepScaling creates the pipeline, pins the vertex at each replica count in turn and measures its throughput,
+// then deletes the pipeline. Speedup and efficiency are relative to one replica and are NaN if one replica was not measured.
+func (r *Runner) SweepScaling(ctx context.Context, obj *unstructured.Unstructured, prom *metrics.PrometheusClient, opts ScalingOptions) (result *ScalingResult, err error) {
+	g, err := pipeline.ParseGraph(obj)
+	if err != nil {
+		return nil, err
+	}
+	if g.Vertex(opts.Vertex) == nil {
+		return nil, fmt.Errorf("pipeline %s has no vertex %s", g.Name, opts.Vertex)
+	}
+	if len(opts.Replicas) == 0 {
+		return nil, fmt.Errorf("no replica counts to sweep")
+	}
+	sinks := g.VerticesOfKind(pipeline.VertexSink)
+
+	record, err := r.Start(ctx, obj)
+	if record == nil {
+		return nil, err
+	}
+
+	defer func() {
+		if teardownErr := r.Teardown(record); teardownErr != nil && err == nil {
+			err = teardownErr
+		}
+	}()
+
+	if err != nil {
+		return nil, err
+	}
+
+	result = &ScalingResult{Record: record, Vertex: opts.Vertex}
+	record.WindowStart = time.Now()
+	defer func() { record.WindowEnd = time.Now() }()
+
+	baseline := math.NaN()
+	for _, replicas := range opts.Replicas {
+		step, err := r.measureReplicas(ctx, prom, record, opts, sinks, replicas)
+		if err != nil {
+			return result, err
+		}
+
+		if replicas == 1 {
+			baseline = float64(step.VertexRate)
+		}
+		step.Speedup = Metric(float64(step.VertexRate) / baseline)
+		step.Efficiency = step.Speedup / Metric(replicas)
+		result.Steps = append(result.Steps, step)
+
+		r.Log.Info("Measured replicas", zap.String("pipeline", record.Pipeline), zap.String("vertex", opts.Vertex),
+			zap.Int64("replicas", replicas), zap.Float64("vertex-rate", float64(step.VertexRate)), zap.Float64("speedup", float64(step.Speedup)))
+	}
+
+	return result, nil
+}
+
+// measureReplicas pins the vertex at replicas, waits for its pods to be ready and to settle, and measures the rates over the hold
+func (r *Runner) measureReplicas(ctx context.Context, prom *metrics.PrometheusClient, rec *RunRecord, opts ScalingOptions, sinks []string, replicas int64) (ScalingStep, error) {
+	step := ScalingStep{Replicas: replicas}
+	if err := pipeline.UpdateVertex(ctx, r.DynamicClient, r.Namespace, rec.Pipeline, opts.Vertex, pipeline.SetReplicas(replicas)); err != nil {
+		return step, err
+	}
+	rec.AddEvent(Event{Time: time.Now(), Type: "scale", Target: opts.Vertex, Detail: fmt.Sprintf("replicas pinned at %d", replicas)})
+	r.Log.Info("Pinned replicas", zap.String("pipeline", rec.Pipeline), zap.String("vertex", opts.Vertex), zap.Int64("replicas", replicas))
+
+	if err := r.waitForReadyReplicas(ctx, rec.Pipeline, opts.Vertex, replicas, opts.ReadyTimeout); err != nil {
+		return step, err
+	}
+	if err := sleep(ctx, opts.Settle); err != nil {
+		return step, err
+	}
+	start := time.Now()
+	if err := sleep(ctx, opts.Hold); err != nil {
+		return step, err
+	}
+	end := time.Now()
+
+	vertexRate, err := prom.ScalarOrNaN(ctx, metrics.ReadRateQuery(r.Namespace, rec.Pipeline, []string{opts.Vertex}, end.Sub(start)), end)
+	if err != nil {
+		return step, err
+	}
+	sinkRate, err := prom.ScalarOrNaN(ctx, metrics.ReadRateQuery(r.Namespace, rec.Pipeline, sinks, end.Sub(start)), end)
+	if err != nil {
+		return step, err
+	}
+	step.VertexRate, step.SinkRate = Metric(vertexRate), Metric(sinkRate)
+
+	return step, nil
+}
+
+// waitForReadyReplicas waits until exactly replicas pods of the vertex exist and all of them are ready
+func (r *Runner) waitForReadyReplicas(ctx context.Context, pipelineName string, vertex string, replicas int64, timeout time.Duration) error {
+	selector := fmt.Sprintf("%s=%s,%s=%s", util.PipelineNameLabel, pipelineName, util.VertexNameLabel, vertex)
+
+	pollCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := wait.PollUntilContextCancel(pollCtx, readyPollInterval, true, func(ctx context.Context) (bool, error) {
+		pods, err := r.KubeClient.CoreV1().Pods(r.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return false, err
+		}
+
+		ready := int64(0)
+		for _, pod := range pods.Items {
+			if pod.DeletionTimestamp != nil {
+				// A terminating pod still counts, the vertex has not finished scaling down
+				return false, nil
+			}
+			for _, condition := range pod.Status.Conditions {
+				if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+					ready++
+				}
+			}
+		}
+
+		return int64(len(pods.Items)) == replicas && ready == replicas, nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("vertex %s did not reach %d ready replicas: %w", vertex, replicas, err)
+	}
+
+	return nil
+}
+
+// Save writes the result as json into dir, returning the path of the file written
+func (result *ScalingResult) Save(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create scaling result directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal scaling result: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("scaling-%s-%s-%s.json", result.Record.Pipeline, result.Vertex,
+		result.Record.CreatedAt.UTC().Format("20060102-150405")))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write scaling result: %w", err)
+	}
+
+	return path, nil
+}
+
+// WriteScalingTable prints one row per replica count
+func WriteScalingTable(out io.Writer, result *ScalingResult) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "replicas\t%s (msg/s)\tsinks (msg/s)\tspeedup\tefficiency (%%)\n", result.Vertex)
+	for _, s := range result.Steps {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", s.Replicas, formatMetric(float64(s.VertexRate), 1), formatMetric(float64(s.SinkRate), 1),
+			formatMetric(float64(s.Speedup), 2), formatMetric(float64(s.Efficiency)*100, 0))
+	}
+
+	return w.Flush()
+}
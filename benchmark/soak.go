@@ -0,0 +1,264 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ayildirim21/numaflow-perfman/metrics"
+)
+
+// Per pod metrics sampled during a soak
+const (
+	soakMemory     = "memory"
+	soakCPU        = "cpu"
+	soakGoroutines = "goroutines"
+	soakThreads    = "threads"
+)
+
+// SoakOptions sets how trends over a soak are judged
+type SoakOptions struct {
+	// MemoryTolerance is the fraction by which a pod's fitted memory may grow over the soak before it is flagged
+	MemoryTolerance float64
+	// MinR2 is how well a line must fit a pod's memory for its growth to count as steady
+	MinR2 float64
+	// ThroughputTolerance is the fraction by which a vertex's fitted throughput may drop over the soak before it is flagged
+	ThroughputTolerance float64
+}
+
+// SoakSampler is an Observer sampling resource usage of every pipeline pod and throughput of every vertex
+type SoakSampler struct {
+	prom      *metrics.PrometheusClient
+	namespace string
+	interval  time.Duration
+	log       *zap.Logger
+
+	mu       sync.Mutex
+	pipeline string
+	pods     map[string]map[string][]metrics.Point
+	vertices map[string][]metrics.Point
+}
+
+// NewSoakSampler returns a sampler querying prom once every interval
+func NewSoakSampler(prom *metrics.PrometheusClient, namespace string, interval time.Duration, log *zap.Logger) *SoakSampler {
+	return &SoakSampler{
+		prom:      prom,
+		namespace: namespace,
+		interval:  interval,
+		log:       log,
+		pods: map[string]map[string][]metrics.Point{
+			soakMemory:     {},
+			soakCPU:        {},
+			soakGoroutines: {},
+			soakThreads:    {},
+		},
+		vertices: map[string][]metrics.Point{},
+	}
+}
+
+// Observe samples every interval until the measurement window closes
+func (s *SoakSampler) Observe(ctx context.Context, rec *RunRecord) error {
+	s.mu.Lock()
+	s.pipeline = rec.Pipeline
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		// A failed sample only leaves a gap in the series, a long soak should not die on it
+		if err := s.sample(ctx, rec.Pipeline, time.Now()); err != nil && ctx.Err() == nil {
+			s.log.Warn("Unable to sample soak metrics", zap.String("pipeline", rec.Pipeline), zap.Error(err))
+		}
+	}
+}
+
+func (s *SoakSampler) sample(ctx context.Context, pipelineName string, at time.Time) error {
+	pods := metrics.PodSelector(s.namespace, pipelineName)
+	rateWindow := max(s.interval, time.Minute)
+	podQueries := map[string]string{
+		soakMemory:     fmt.Sprintf("sum by (pod) (container_memory_working_set_bytes%s)", pods),
+		soakCPU:        fmt.Sprintf("sum by (pod) (rate(container_cpu_usage_seconds_total%s[%ds]))", pods, int(rateWindow.Seconds())),
+		soakGoroutines: fmt.Sprintf(`sum by (pod) (go_goroutines{namespace="%s", pod=~"%s-.*"})`, s.namespace, pipelineName),
+		soakThreads:    fmt.Sprintf(`sum by (pod) (go_threads{namespace="%s", pod=~"%s-.*"})`, s.namespace, pipelineName),
+	}
+
+	for metric, query := range podQueries {
+		samples, err := s.prom.Query(ctx, query, at)
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		for _, sample := range samples {
+			pod := sample.Labels["pod"]
+			s.pods[metric][pod] = append(s.pods[metric][pod], metrics.Point{Time: at, Value: sample.Value})
+		}
+		s.mu.Unlock()
+	}
+
+	query := fmt.Sprintf("sum by (vertex) (rate(forwarder_read_total%s[%ds]))",
+		metrics.PipelineSelector(s.namespace, pipelineName), int(rateWindow.Seconds()))
+	samples, err := s.prom.Query(ctx, query, at)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	for _, sample := range samples {
+		vertex := sample.Labels["vertex"]
+		s.vertices[vertex] = append(s.vertices[vertex], metrics.Point{Time: at, Value: sample.Value})
+	}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// PodTrend holds the fitted trends of one pod over a soak; slopes are per hour
+type PodTrend struct {
+	Pod             string  `json:"pod"`
+	MemorySlope     float64 `json:"memoryBytesPerHour"`
+	MemoryGrowth    Metric  `json:"memoryGrowth"`
+	MemoryR2        float64 `json:"memoryR2"`
+	CPUSlope        float64 `json:"cpuCoresPerHour"`
+	GoroutinesSlope float64 `json:"goroutinesPerHour"`
+	ThreadsSlope    float64 `json:"threadsPerHour"`
+	Samples         int     `json:"samples"`
+	MemoryLeak      bool    `json:"memoryLeak"`
+}
+
+// VertexTrend holds the fitted throughput trend of one vertex over a soak
+type VertexTrend struct {
+	Vertex    string  `json:"vertex"`
+	StartRate float64 `json:"startRate"`
+	EndRate   float64 `json:"endRate"`
+	Change    Metric  `json:"change"`
+	Samples   int     `json:"samples"`
+	Degraded  bool    `json:"degraded"`
+}
+
+// SoakSummary is the outcome of a soak
+type SoakSummary struct {
+	Pipeline string        `json:"pipeline"`
+	Options  SoakOptions   `json:"options"`
+	Pods     []PodTrend    `json:"pods"`
+	Vertices []VertexTrend `json:"vertices"`
+}
+
+// Summary fits trends to everything sampled so far and flags leaking pods and degrading vertices
+func (s *SoakSampler) Summary(opts SoakOptions) *SoakSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := &SoakSummary{Pipeline: s.pipeline, Options: opts}
+
+	for pod, points := range s.pods[soakMemory] {
+		memory := metrics.LinearFit(points)
+		trend := PodTrend{
+			Pod:             pod,
+			MemorySlope:     memory.Slope,
+			MemoryGrowth:    Metric(memory.RelativeChange()),
+			MemoryR2:        memory.R2,
+			CPUSlope:        metrics.LinearFit(s.pods[soakCPU][pod]).Slope,
+			GoroutinesSlope: metrics.LinearFit(s.pods[soakGoroutines][pod]).Slope,
+			ThreadsSlope:    metrics.LinearFit(s.pods[soakThreads][pod]).Slope,
+			Samples:         len(points),
+		}
+		trend.MemoryLeak = memory.Slope > 0 && memory.R2 >= opts.MinR2 && float64(trend.MemoryGrowth) > opts.MemoryTolerance
+		summary.Pods = append(summary.Pods, trend)
+	}
+	sort.Slice(summary.Pods, func(i, j int) bool { return summary.Pods[i].Pod < summary.Pods[j].Pod })
+
+	for vertex, points := range s.vertices {
+		fit := metrics.LinearFit(points)
+		trend := VertexTrend{
+			Vertex:    vertex,
+			StartRate: fit.Intercept,
+			EndRate:   fit.Intercept + fit.Slope*fit.Hours,
+			Change:    Metric(fit.RelativeChange()),
+			Samples:   len(points),
+		}
+		trend.Degraded = !math.IsNaN(float64(trend.Change)) && float64(trend.Change) < -opts.ThroughputTolerance
+		summary.Vertices = append(summary.Vertices, trend)
+	}
+	sort.Slice(summary.Vertices, func(i, j int) bool { return summary.Vertices[i].Vertex < summary.Vertices[j].Vertex })
+
+	return summary
+}
+
+// Flagged reports whether any pod leaks memory or any vertex degrades
+func (summary *SoakSummary) Flagged() bool {
+	for _, p := range summary.Pods {
+		if p.MemoryLeak {
+			return true
+		}
+	}
+	for _, v := range summary.Vertices {
+		if v.Degraded {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Save writes the summary as json into dir, returning the path of the file written
+func (summary *SoakSummary) Save(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create soak summary directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal soak summary: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("soak-%s-%s.json", summary.Pipeline, time.Now().UTC().Format("20060102-150405")))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write soak summary: %w", err)
+	}
+
+	return path, nil
+}
+
+// WriteSoakSummary prints the per pod and per vertex trends of a soak
+func WriteSoakSummary(out io.Writer, summary *SoakSummary) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "pod\tmemory (MiB/h)\tmemory growth (%)\tmemory r2\tcpu (mcores/h)\tgoroutines/h\tthreads/h\tsamples\tflag")
+	for _, p := range summary.Pods {
+		flag := ""
+		if p.MemoryLeak {
+			flag = "MEMORY LEAK"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n", p.Pod,
+			formatMetric(p.MemorySlope/(1<<20), 2), formatMetric(float64(p.MemoryGrowth)*100, 1), formatMetric(p.MemoryR2, 2),
+			formatMetric(p.CPUSlope*1000, 1), formatMetric(p.GoroutinesSlope, 1), formatMetric(p.ThreadsSlope, 1), p.Samples, flag)
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "vertex\tstart (msg/s)\tend (msg/s)\tchange (%)\tsamples\tflag")
+	for _, v := range summary.Vertices {
+		flag := ""
+		if v.Degraded {
+			flag = "THROUGHPUT DEGRADED"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", v.Vertex,
+			formatMetric(v.StartRate, 1), formatMetric(v.EndRate, 1), formatMetric(float64(v.Change)*100, 1), v.Samples, flag)
+	}
+
+	return w.Flush()
+}
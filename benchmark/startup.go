@@ -0,0 +1,284 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/ayildirim21/numaflow-perfman/metrics"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+// Stages a pipeline goes through from creation until messages reach its sinks, in the order they are expected
+const (
+	StageBuffersCreated    = "isb buffers created"
+	StagePodsScheduled     = "pods scheduled"
+	StageContainersStarted = "containers started"
+	StageRunning           = "pipeline running"
+	StageFirstRead         = "first read at source"
+	StageFirstWrite        = "first write at sink"
+)
+
+// StartupStages lists every stage in order
+var StartupStages = []string{
+	StageBuffersCreated,
+	StagePodsScheduled,
+	StageContainersStarted,
+	StageRunning,
+	StageFirstRead,
+	StageFirstWrite,
+}
+
+// firstMessagePollInterval is how often prometheus is checked while waiting for the first message at the sinks
+const firstMessagePollInterval = 5 * time.Second
+
+// StartupTiming is the time from the creation of a pipeline until each stage was reached, and how long its teardown took.
+// Stages that could not be observed are missing from Stages.
+type StartupTiming struct {
+	Pipeline  string                   `json:"pipeline"`
+	CreatedAt time.Time                `json:"createdAt"`
+	Stages    map[string]time.Duration `json:"stages"`
+	Teardown  time.Duration            `json:"teardown"`
+}
+
+// MeasureStartup creates the pipeline, waits until messages reach its sinks or timeout passes, times every
+// startup stage, then deletes the pipeline and times its teardown.
+//
+// Stages come from Kubernetes timestamps, which have a resolution of a second, and from the first nonzero
+// sample of the forwarder counters, which is only as precise as the Prometheus scrape interval.
+func (r *Runner) MeasureStartup(ctx context.Context, obj *unstructured.Unstructured, prom *metrics.PrometheusClient, timeout time.Duration) (timing *StartupTiming, err error) {
+	g, err := pipeline.ParseGraph(obj)
+	if err != nil {
+		return nil, err
+	}
+	sources, sinks := g.VerticesOfKind(pipeline.VertexSource), g.VerticesOfKind(pipeline.VertexSink)
+
+	record, err := r.Start(ctx, obj)
+	if record == nil {
+		return nil, err
+	}
+
+	defer func() {
+		teardownStart := time.Now()
+		if teardownErr := r.Teardown(record); teardownErr != nil {
+			if err == nil {
+				err = teardownErr
+			}
+			return
+		}
+		if timing != nil {
+			timing.Teardown = record.DeletedAt.Sub(teardownStart)
+		}
+	}()
+
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := r.DynamicClient.Resource(pipeline.GVR).Namespace(r.Namespace).Get(ctx, record.Pipeline, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pipeline %s: %w", record.Pipeline, err)
+	}
+
+	timing = &StartupTiming{
+		Pipeline:  record.Pipeline,
+		CreatedAt: created.GetCreationTimestamp().Time,
+		Stages:    map[string]time.Duration{},
+	}
+	timing.Stages[StageRunning] = record.RunningAt.Sub(timing.CreatedAt)
+
+	writeQuery := fmt.Sprintf("sum(forwarder_write_total%s)", metrics.PipelineSelector(r.Namespace, record.Pipeline, sinks...))
+	if err := r.waitForFirstMessage(ctx, prom, record.Pipeline, writeQuery, timeout); err != nil {
+		return timing, err
+	}
+
+	if err := r.kubernetesStages(ctx, timing); err != nil {
+		return timing, err
+	}
+
+	readQuery := fmt.Sprintf("sum(forwarder_read_total%s)", metrics.PipelineSelector(r.Namespace, record.Pipeline, sources...))
+	for stage, query := range map[string]string{StageFirstRead: readQuery, StageFirstWrite: writeQuery} {
+		first, found, err := firstNonzeroSample(ctx, prom, query, timing.CreatedAt, time.Now())
+		if err != nil {
+			return timing, err
+		}
+		if found {
+			timing.Stages[stage] = first.Sub(timing.CreatedAt)
+		}
+	}
+
+	r.Log.Info("Measured startup", zap.String("pipeline", record.Pipeline), zap.Any("stages", timing.Stages))
+	return timing, nil
+}
+
+// waitForFirstMessage polls query until it is nonzero, giving up without an error after timeout
+func (r *Runner) waitForFirstMessage(ctx context.Context, prom *metrics.PrometheusClient, pipelineName string, query string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		written, err := prom.Scalar(ctx, query, time.Now())
+		if err != nil && !errors.Is(err, metrics.ErrNoData) {
+			return err
+		}
+		if err == nil && written > 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			r.Log.Warn("No message reached the sinks in time", zap.String("pipeline", pipelineName), zap.Duration("timeout", timeout))
+			return nil
+		}
+
+		if err := sleep(ctx, firstMessagePollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// kubernetesStages times the stages recorded by Kubernetes: the completion of the buffer creation job,
+// the last vertex pod to be scheduled and the last vertex container to start
+func (r *Runner) kubernetesStages(ctx context.Context, timing *StartupTiming) error {
+	selector := fmt.Sprintf("%s=%s", util.PipelineNameLabel, timing.Pipeline)
+
+	jobs, err := r.KubeClient.BatchV1().Jobs(r.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("failed to list jobs of pipeline %s: %w", timing.Pipeline, err)
+	}
+	var buffersCreated time.Time
+	for _, job := range jobs.Items {
+		if job.Status.CompletionTime != nil && job.Status.CompletionTime.Time.After(buffersCreated) {
+			buffersCreated = job.Status.CompletionTime.Time
+		}
+	}
+	if !buffersCreated.IsZero() {
+		timing.Stages[StageBuffersCreated] = buffersCreated.Sub(timing.CreatedAt)
+	}
+
+	pods, err := r.KubeClient.CoreV1().Pods(r.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector + "," + util.VertexNameLabel})
+	if err != nil {
+		return fmt.Errorf("failed to list pods of pipeline %s: %w", timing.Pipeline, err)
+	}
+
+	var scheduled, started time.Time
+	scheduledAll, startedAll := len(pods.Items) > 0, len(pods.Items) > 0
+	for _, pod := range pods.Items {
+		podScheduled := false
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionTrue {
+				podScheduled = true
+				if condition.LastTransitionTime.Time.After(scheduled) {
+					scheduled = condition.LastTransitionTime.Time
+				}
+			}
+		}
+		scheduledAll = scheduledAll && podScheduled
+
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			if status.State.Running == nil {
+				// Init containers have terminated by now, only main containers must be running
+				if status.State.Terminated == nil {
+					startedAll = false
+				}
+				continue
+			}
+			if status.State.Running.StartedAt.Time.After(started) {
+				started = status.State.Running.StartedAt.Time
+			}
+		}
+	}
+
+	if scheduledAll {
+		timing.Stages[StagePodsScheduled] = scheduled.Sub(timing.CreatedAt)
+	}
+	if startedAll && !started.IsZero() {
+		timing.Stages[StageContainersStarted] = started.Sub(timing.CreatedAt)
+	}
+
+	return nil
+}
+
+// firstNonzeroSample returns the time of the first sample of query in [start, end] that is above zero
+func firstNonzeroSample(ctx context.Context, prom *metrics.PrometheusClient, query string, start time.Time, end time.Time) (time.Time, bool, error) {
+	// Stay well below the 11000 points prometheus allows in a single range query
+	step := max(time.Second, end.Sub(start)/10000)
+	series, err := prom.QueryRange(ctx, query, start, end, step)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	for _, s := range series {
+		for _, p := range s.Points {
+			if p.Value > 0 {
+				return p.Time, true, nil
+			}
+		}
+	}
+
+	return time.Time{}, false, nil
+}
+
+// SaveStartupTimings writes timings as json into dir, returning the path of the file written
+func SaveStartupTimings(dir string, timings []*StartupTiming) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create startup timing directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(timings, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal startup timings: %w", err)
+	}
+
+	name := "startup"
+	if len(timings) > 0 {
+		name += "-" + timings[0].Pipeline
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.json", name, time.Now().UTC().Format("20060102-150405")))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write startup timings: %w", err)
+	}
+
+	return path, nil
+}
+
+// WriteStartupTable prints the distribution of every stage, in seconds from pipeline creation, over all timings
+func WriteStartupTable(out io.Writer, timings []*StartupTiming) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "stage\truns\tmin (s)\tp50 (s)\tp90 (s)\tmax (s)")
+
+	row := func(stage string, values []float64) {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", stage, len(values),
+			formatMetric(metrics.Percentile(values, 0), 1), formatMetric(metrics.Percentile(values, 50), 1),
+			formatMetric(metrics.Percentile(values, 90), 1), formatMetric(metrics.Percentile(values, 100), 1))
+	}
+
+	for _, stage := range StartupStages {
+		var values []float64
+		for _, t := range timings {
+			if d, ok := t.Stages[stage]; ok {
+				values = append(values, d.Seconds())
+			}
+		}
+		row(stage, values)
+	}
+
+	var teardown []float64
+	for _, t := range timings {
+		if t.Teardown > 0 {
+			teardown = append(teardown, t.Teardown.Seconds())
+		}
+	}
+	row("teardown", teardown)
+
+	return w.Flush()
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/ayildirim21/numaflow-perfman/benchmark"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+var BackpressureFile string
+var BackpressureScenario string
+var BackpressureValues []string
+var BackpressureVertex string
+var BackpressureDuration time.Duration
+var BackpressureWarmup time.Duration
+var BackpressureStep time.Duration
+var BackpressureMinRateDrop float64
+var BackpressureOutputDir string
+
+// backpressureCmd represents the backpressure command
+var backpressureCmd = &cobra.Command{
+	Use:   "backpressure",
+	Short: "Verify that backpressure engages in front of a slow vertex",
+	Long: "The backpressure command runs the built-in backpressure scenario, or --file, in which a slow vertex sits behind " +
+		"small buffers. It reports the usage of the buffers in front of --vertex from the JetStream metrics, next to the upstream " +
+		"write rate and the vertex's read rate, from the moment the pipeline runs until the measurement window closes. " +
+		"The command fails if no buffer reached its usage limit or the upstream rate did not drop",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		values, err := pipeline.ParseValues(BackpressureValues)
+		if err != nil {
+			return err
+		}
+
+		scenario := BackpressureScenario
+		if BackpressureFile != "" {
+			scenario = ""
+		}
+
+		name, manifest, err := renderPipeline(BackpressureFile, scenario, values)
+		if err != nil {
+			return err
+		}
+
+		obj, err := preparePipeline(name, manifest)
+		if err != nil {
+			return err
+		}
+
+		// Fail before deploying anything if the run could never show backpressure on the vertex
+		g, err := pipeline.ParseGraph(obj)
+		if err != nil {
+			return err
+		}
+		if _, err := benchmark.BackpressureUpstream(g, BackpressureVertex); err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		prom, err := newPrometheusClient()
+		if err != nil {
+			return err
+		}
+
+		runner := benchmark.Runner{
+			DynamicClient: dynamicClient,
+			KubeClient:    kubeClient,
+			Namespace:     util.PerfmanNamespace,
+			Log:           log,
+		}
+
+		rec, runErr := runner.Run(ctx, obj, benchmark.RunOptions{Warmup: BackpressureWarmup, Duration: BackpressureDuration})
+		if rec == nil {
+			if errors.Is(runErr, context.Canceled) {
+				return errors.New("backpressure run interrupted")
+			}
+			return fmt.Errorf("backpressure run failed: %w", runErr)
+		}
+
+		// A run whose teardown failed still measured a full window, keep and analyze it before reporting the failure
+		path, err := rec.Save(BackpressureOutputDir)
+		if err != nil {
+			return err
+		}
+		log.Info("Saved run record", zap.String("path", path))
+
+		// Buffers fill from the moment the pipeline runs, the warmup is part of what is worth seeing
+		report, err := benchmark.AnalyzeBackpressure(ctx, prom, util.PerfmanNamespace, obj, benchmark.BackpressureOptions{
+			Vertex:      BackpressureVertex,
+			Step:        BackpressureStep,
+			MinRateDrop: BackpressureMinRateDrop,
+		}, rec.RunningAt, rec.WindowEnd)
+		if err != nil {
+			return fmt.Errorf("failed to analyze backpressure: %w", err)
+		}
+
+		if err := benchmark.WriteBackpressureReport(os.Stdout, report); err != nil {
+			return err
+		}
+
+		path, err = report.Save(BackpressureOutputDir)
+		if err != nil {
+			return err
+		}
+		log.Info("Saved backpressure report", zap.String("path", path))
+
+		if runErr != nil {
+			return fmt.Errorf("backpressure run failed: %w", runErr)
+		}
+		if !report.Engaged {
+			return errors.New("backpressure did not engage")
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backpressureCmd)
+
+	backpressureCmd.Flags().StringVarP(&BackpressureFile, "file", "f", "", "Pipeline manifest to run instead of the built-in scenario")
+	backpressureCmd.Flags().StringVar(&BackpressureScenario, "scenario", "backpressure", "Built-in pipeline scenario to run")
+	backpressureCmd.Flags().StringArrayVar(&BackpressureValues, "set", nil, "Template value in the form key=value, can be repeated")
+	backpressureCmd.Flags().StringVar(&BackpressureVertex, "vertex", "slow", "Slow vertex whose input buffers are expected to fill")
+	backpressureCmd.Flags().DurationVar(&BackpressureDuration, "duration", 5*time.Minute, "Length of the measurement window")
+	backpressureCmd.Flags().DurationVar(&BackpressureWarmup, "warmup", time.Minute, "How long the pipeline runs before the measurement window opens")
+	backpressureCmd.Flags().DurationVar(&BackpressureStep, "step", 15*time.Second, "Resolution of the reported timelines")
+	backpressureCmd.Flags().Float64Var(&BackpressureMinRateDrop, "min-rate-drop", 0.1, "Fraction the upstream rate must fall from its peak for backpressure to count as engaged")
+	backpressureCmd.Flags().StringVarP(&BackpressureOutputDir, "output-dir", "o", util.RunRecordDir, "Directory the backpressure report and run record are written to")
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/ayildirim21/numaflow-perfman/benchmark"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+var CapacityFile string
+var CapacityScenario string
+var CapacityValues []string
+var CapacityOutputDir string
+var CapacityMaxP99 time.Duration
+var capacityOpts benchmark.CapacityOptions
+
+// capacityCmd represents the capacity command
+var capacityCmd = &cobra.Command{
+	Use:   "capacity",
+	Short: "Find the maximum sustainable throughput of a pipeline",
+	Long: "The capacity command binary searches the rate of a pipeline's generator. Each rate is held for a window " +
+		"and judged by pending message growth, ack rate against read rate and p99 latency; the highest rate sustained " +
+		"is reported together with the metric that limited it",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		values, err := pipeline.ParseValues(CapacityValues)
+		if err != nil {
+			return err
+		}
+
+		name, manifest, err := renderPipeline(CapacityFile, CapacityScenario, values)
+		if err != nil {
+			return err
+		}
+
+		obj, err := preparePipeline(name, manifest)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		prom, err := newPrometheusClient()
+		if err != nil {
+			return err
+		}
+
+		runner := benchmark.Runner{
+			DynamicClient: dynamicClient,
+			KubeClient:    kubeClient,
+			Namespace:     util.PerfmanNamespace,
+			Log:           log,
+		}
+
+		opts := capacityOpts
+		opts.MaxP99 = float64(CapacityMaxP99) / float64(time.Millisecond)
+
+		result, err := runner.FindCapacity(ctx, obj, prom, opts)
+
+		// A search whose teardown failed still found the capacity, keep its record and report it
+		if result != nil {
+			path, err := result.Record.Save(CapacityOutputDir)
+			if err != nil {
+				return err
+			}
+			log.Info("Saved run record", zap.String("path", path))
+
+			if err := benchmark.WriteCapacityTable(os.Stdout, result); err != nil {
+				return err
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("capacity search failed: %w", err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(capacityCmd)
+
+	capacityCmd.Flags().StringVarP(&CapacityFile, "file", "f", util.DefaultPipelineFile, "Pipeline manifest to search")
+	capacityCmd.Flags().StringVar(&CapacityScenario, "scenario", "", "Built-in pipeline scenario to search instead of --file")
+	capacityCmd.Flags().StringArrayVar(&CapacityValues, "set", nil, "Template value in the form key=value, can be repeated")
+	capacityCmd.Flags().StringVarP(&CapacityOutputDir, "output-dir", "o", util.RunRecordDir, "Directory the run record is written to")
+	capacityCmd.Flags().StringVar(&capacityOpts.Vertex, "vertex", "", "Generator vertex whose rpu is searched, by default the only generator")
+	capacityCmd.Flags().Int64Var(&capacityOpts.MinRPU, "min-rpu", 1, "Lowest rpu tried")
+	capacityCmd.Flags().Int64Var(&capacityOpts.MaxRPU, "max-rpu", 10000, "Highest rpu tried")
+	capacityCmd.Flags().Int64Var(&capacityOpts.Precision, "precision", 10, "Stop once the search range is at most this many rpu wide")
+	capacityCmd.Flags().DurationVar(&capacityOpts.Settle, "settle", 30*time.Second, "How long each rate runs before it is measured")
+	capacityCmd.Flags().DurationVar(&capacityOpts.Hold, "hold", 2*time.Minute, "How long each rate is measured for")
+	capacityCmd.Flags().Float64Var(&capacityOpts.MaxPendingGrowth, "max-pending-growth", 1, "Largest growth of pending messages per second a sustained rate may cause")
+	capacityCmd.Flags().Float64Var(&capacityOpts.MinAckRatio, "min-ack-ratio", 0.95, "Smallest ratio of ack rate to read rate a sustained rate may cause")
+	capacityCmd.Flags().DurationVar(&CapacityMaxP99, "max-p99", 0, "Largest p99 latency a sustained rate may cause, 0 disables the check")
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/ayildirim21/numaflow-perfman/benchmark"
+	"github.com/ayildirim21/numaflow-perfman/metrics"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+var FleetFile string
+var FleetScenario string
+var FleetValues []string
+var FleetCopies []int
+var FleetStartTimeout time.Duration
+var FleetWarmup time.Duration
+var FleetDuration time.Duration
+var FleetCooldown time.Duration
+var FleetOutputDir string
+
+// fleetCmd represents the fleet command
+var fleetCmd = &cobra.Command{
+	Use:   "fleet",
+	Short: "Load test many concurrent copies of a pipeline",
+	Long: "The fleet command deploys N copies of a pipeline at once, each named <pipeline>-c<i>, for every N given by --copies. " +
+		"For each N it reports how long the copies took to start, the controller's p99 reconcile latency, the aggregate sink " +
+		"throughput and how fairly it was shared between the copies (Jain's index, 1 is perfectly fair). " +
+		"Reconcile latency is only available when Prometheus scrapes the numaflow controller, which perfman setup configures",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, n := range FleetCopies {
+			if n < 1 {
+				return errors.New("--copies must all be at least 1")
+			}
+		}
+
+		values, err := pipeline.ParseValues(FleetValues)
+		if err != nil {
+			return err
+		}
+
+		name, manifest, err := renderPipeline(FleetFile, FleetScenario, values)
+		if err != nil {
+			return err
+		}
+
+		obj, err := preparePipeline(name, manifest)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		prom, err := newPrometheusClient()
+		if err != nil {
+			return err
+		}
+
+		if _, err := prom.Scalar(ctx, metrics.ReconcileSeriesQuery(), time.Now()); errors.Is(err, metrics.ErrNoData) {
+			log.Warn("Prometheus has no numaflow controller metrics, reconcile latency will not be reported. " +
+				"Run perfman setup to have Prometheus scrape the controller")
+		} else if err != nil {
+			return err
+		}
+
+		runner := benchmark.Runner{
+			DynamicClient: dynamicClient,
+			KubeClient:    kubeClient,
+			Namespace:     util.PerfmanNamespace,
+			Log:           log,
+		}
+
+		levels := runner.RunFleet(ctx, obj, prom, benchmark.FleetOptions{
+			Copies:       FleetCopies,
+			StartTimeout: FleetStartTimeout,
+			Warmup:       FleetWarmup,
+			Duration:     FleetDuration,
+			Cooldown:     FleetCooldown,
+		})
+
+		if err := benchmark.WriteFleetTable(os.Stdout, levels); err != nil {
+			return err
+		}
+
+		path, err := benchmark.SaveFleetLevels(FleetOutputDir, obj.GetName(), levels)
+		if err != nil {
+			return err
+		}
+		log.Info("Saved fleet results", zap.String("path", path))
+
+		if ctx.Err() != nil {
+			return errors.New("fleet interrupted")
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fleetCmd)
+
+	fleetCmd.Flags().StringVarP(&FleetFile, "file", "f", util.DefaultPipelineFile, "Pipeline manifest to copy")
+	fleetCmd.Flags().StringVar(&FleetScenario, "scenario", "", "Built-in pipeline scenario to copy instead of --file")
+	fleetCmd.Flags().StringArrayVar(&FleetValues, "set", nil, "Template value in the form key=value, can be repeated")
+	fleetCmd.Flags().IntSliceVar(&FleetCopies, "copies", []int{1, 10, 50, 100}, "Numbers of concurrent copies to run, one level each")
+	fleetCmd.Flags().DurationVar(&FleetStartTimeout, "start-timeout", 15*time.Minute, "How long all copies of a level may take to reach Running")
+	fleetCmd.Flags().DurationVar(&FleetWarmup, "warmup", 2*time.Minute, "How long the copies run before the measurement window opens")
+	fleetCmd.Flags().DurationVar(&FleetDuration, "duration", 5*time.Minute, "Length of the measurement window of each level")
+	fleetCmd.Flags().DurationVar(&FleetCooldown, "cooldown", time.Minute, "Pause between levels")
+	fleetCmd.Flags().StringVarP(&FleetOutputDir, "output-dir", "o", util.RunRecordDir, "Directory fleet results are written to")
+}
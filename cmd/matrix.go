@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/ayildirim21/numaflow-perfman/benchmark"
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+var MatrixOutputDir string
+
+// matrixCmd represents the matrix command
+var matrixCmd = &cobra.Command{
+	Use:   "matrix SPEC",
+	Short: "Run a parameter sweep of a templated pipeline",
+	Long: "The matrix command expands a matrix spec into one run per combination of its parameters, executes the runs " +
+		"sequentially with a cooldown in between, and reports throughput, latency and resource usage for each combination",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := benchmark.LoadMatrix(args[0])
+		if err != nil {
+			return err
+		}
+
+		prepare := func(values map[string]string) (*unstructured.Unstructured, error) {
+			name, manifest, err := renderPipeline(m.File, m.Scenario, values)
+			if err != nil {
+				return nil, err
+			}
+
+			return preparePipeline(name, manifest)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		prom, err := newPrometheusClient()
+		if err != nil {
+			return err
+		}
+
+		runner := benchmark.Runner{
+			DynamicClient: dynamicClient,
+			KubeClient:    kubeClient,
+			Namespace:     util.PerfmanNamespace,
+			Log:           log,
+		}
+
+		results := runner.RunMatrix(ctx, m, prepare, prom)
+
+		failed := 0
+		for _, result := range results {
+			if result.Err != nil {
+				failed++
+			}
+			if result.Record == nil {
+				continue
+			}
+			if _, err := result.Record.Save(MatrixOutputDir); err != nil {
+				log.Warn("Unable to save run record", zap.String("pipeline", result.Record.Pipeline), zap.Error(err))
+			}
+		}
+
+		parameters := m.ParameterNames()
+		if err := benchmark.WriteMatrixTable(os.Stdout, parameters, results); err != nil {
+			return err
+		}
+
+		csvPath := filepath.Join(MatrixOutputDir, fmt.Sprintf("matrix-%s.csv", time.Now().UTC().Format("20060102-150405")))
+		if err := benchmark.WriteMatrixCSV(csvPath, parameters, results); err != nil {
+			return err
+		}
+		log.Info("Saved matrix results", zap.String("path", csvPath))
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d matrix runs failed", failed, len(results))
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("matrix interrupted after %d runs", len(results))
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(matrixCmd)
+
+	matrixCmd.Flags().StringVarP(&MatrixOutputDir, "output-dir", "o", util.RunRecordDir, "Directory run records and the result table are written to")
+}
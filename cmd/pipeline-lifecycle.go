@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+var LifecycleTimeout time.Duration
+
+type lifecycleAction func(ctx context.Context, name string) (time.Duration, error)
+
+// newLifecycleCmd builds a pipeline subcommand that performs a lifecycle transition and reports how long it took
+func newLifecycleCmd(use string, short string, long string, done string, action lifecycleAction) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " NAME",
+		Short: short,
+		Long:  long,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), LifecycleTimeout)
+			defer cancel()
+
+			took, err := action(ctx, args[0])
+			if err != nil {
+				return err
+			}
+
+			log.Info(done, zap.String("pipeline", args[0]), zap.Duration("took", took))
+			return nil
+		},
+	}
+}
+
+var pipelinePauseCmd = newLifecycleCmd("pause", "Pause a pipeline",
+	"The pause command drains a pipeline and waits until it is paused", "Pipeline paused",
+	func(ctx context.Context, name string) (time.Duration, error) {
+		return pipeline.Pause(ctx, dynamicClient, util.PerfmanNamespace, name, log)
+	})
+
+var pipelineResumeCmd = newLifecycleCmd("resume", "Resume a paused pipeline",
+	"The resume command resumes a paused pipeline and waits until it is running", "Pipeline resumed",
+	func(ctx context.Context, name string) (time.Duration, error) {
+		return pipeline.Resume(ctx, dynamicClient, util.PerfmanNamespace, name, log)
+	})
+
+var pipelineRestartCmd = newLifecycleCmd("restart", "Restart a pipeline",
+	"The restart command pauses a pipeline, then resumes it and waits until it is running again", "Pipeline restarted",
+	func(ctx context.Context, name string) (time.Duration, error) {
+		return pipeline.Restart(ctx, dynamicClient, util.PerfmanNamespace, name, log)
+	})
+
+var pipelineDeleteCmd = newLifecycleCmd("delete", "Delete a pipeline",
+	"The delete command deletes a pipeline and waits until all of its pods are gone", "Pipeline deleted",
+	func(ctx context.Context, name string) (time.Duration, error) {
+		return pipeline.Delete(ctx, dynamicClient, kubeClient, util.PerfmanNamespace, name, log)
+	})
+
+func init() {
+	for _, c := range []*cobra.Command{pipelinePauseCmd, pipelineResumeCmd, pipelineRestartCmd, pipelineDeleteCmd} {
+		pipelineCmd.AddCommand(c)
+		c.Flags().DurationVar(&LifecycleTimeout, "timeout", 10*time.Minute, "How long to wait for the transition to complete")
+	}
+}
@@ -2,35 +2,255 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
 	"github.com/ayildirim21/numaflow-perfman/util"
 )
 
+var PipelineFile string
+var PipelineValues []string
+
+var pipelineGvro = util.GVRObject{
+	Group:     pipeline.GVR.Group,
+	Version:   pipeline.GVR.Version,
+	Resource:  pipeline.GVR.Resource,
+	Namespace: util.PerfmanNamespace,
+}
+
 // pipelineCmd represents the pipeline command
 var pipelineCmd = &cobra.Command{
 	Use:   "pipeline",
-	Short: "Apply the base numaflow pipeline",
-	Long:  "Apply the base numaflow pipeline",
+	Short: "Apply a numaflow pipeline",
+	Long: "Apply the numaflow pipeline(s) given by --file, or the base pipeline by default. " +
+		"Manifests are rendered as templates with the values given by --set before being applied",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		pipelineGvro := util.GVRObject{
-			Group:     "numaflow.numaproj.io",
-			Version:   "v1alpha1",
-			Resource:  "pipelines",
-			Namespace: util.PerfmanNamespace,
+		values, err := pipeline.ParseValues(PipelineValues)
+		if err != nil {
+			return err
+		}
+
+		files, err := manifestFiles(PipelineFile)
+		if err != nil {
+			return err
 		}
 
-		if err := pipelineGvro.CreateResource("default/pipeline.yaml", dynamicClient, log); err != nil {
-			return fmt.Errorf("failed to apply base pipeline: %w", err)
+		for _, filename := range files {
+			manifest, err := renderManifestFile(filename, values)
+			if err != nil {
+				return err
+			}
+
+			if _, err := applyPipeline(filename, manifest); err != nil {
+				return fmt.Errorf("failed to apply pipeline %s: %w", filename, err)
+			}
 		}
 
 		return nil
 	},
 }
 
+// pipelineLintCmd represents the pipeline lint command
+var pipelineLintCmd = &cobra.Command{
+	Use:   "lint [FILE]",
+	Short: "Check the topology of a pipeline",
+	Long: "The lint command parses the vertices and edges of a pipeline into a graph and reports unreachable vertices, " +
+		"edges to missing vertices, sinks with outgoing edges and unsupported cycles, along with benchmarking warnings",
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filename := util.DefaultPipelineFile
+		if len(args) == 1 {
+			filename = args[0]
+		}
+
+		values, err := pipeline.ParseValues(PipelineValues)
+		if err != nil {
+			return err
+		}
+
+		manifest, err := renderManifestFile(filename, values)
+		if err != nil {
+			return err
+		}
+
+		return lintPipeline(filename, manifest)
+	},
+}
+
+// pipelineListCmd represents the pipeline list command
+var pipelineListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the built-in pipeline scenarios",
+	Long:  "The list command shows the built-in pipeline scenarios together with the knobs each of them accepts through --set",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, s := range pipeline.Library {
+			fmt.Fprintf(w, "%s\t%s\n", s.Name, s.Description)
+			for _, k := range s.Knobs {
+				fmt.Fprintf(w, "  %s=%s\t%s\n", k.Name, k.Default, k.Description)
+			}
+			fmt.Fprintln(w)
+		}
+
+		return w.Flush()
+	},
+}
+
+// pipelineApplyCmd represents the pipeline apply command
+var pipelineApplyCmd = &cobra.Command{
+	Use:   "apply SCENARIO",
+	Short: "Apply a built-in pipeline scenario",
+	Long:  "The apply command renders a built-in pipeline scenario with the knob values given by --set and applies it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		values, err := pipeline.ParseValues(PipelineValues)
+		if err != nil {
+			return err
+		}
+
+		name, manifest, err := renderPipeline("", args[0], values)
+		if err != nil {
+			return err
+		}
+
+		if _, err := applyPipeline(name, manifest); err != nil {
+			return fmt.Errorf("failed to apply scenario %s: %w", name, err)
+		}
+
+		return nil
+	},
+}
+
+// manifestFiles expands a file or directory path into the manifest files it refers to
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", path, err)
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read directory %s: %w", path, err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no yaml manifests found in %s", path)
+	}
+
+	return files, nil
+}
+
+// renderManifestFile reads a manifest file and renders it as a template with the given values
+func renderManifestFile(filename string, values map[string]string) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", filename, err)
+	}
+
+	return pipeline.Render(filepath.Base(filename), data, values)
+}
+
+// renderPipeline renders either a built-in scenario or a manifest file with the given values,
+// returning a name to report issues against along with the rendered manifest
+func renderPipeline(filename string, scenarioName string, values map[string]string) (string, []byte, error) {
+	if scenarioName == "" {
+		manifest, err := renderManifestFile(filename, values)
+		return filename, manifest, err
+	}
+
+	scenario, err := pipeline.LookupScenario(scenarioName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	manifest, err := scenario.Render(values)
+	return scenario.Name, manifest, err
+}
+
+// preparePipeline validates and lints a rendered pipeline manifest, returning the parsed pipeline
+func preparePipeline(name string, manifest []byte) (*unstructured.Unstructured, error) {
+	if err := validateManifestData(name, manifest); err != nil {
+		return nil, err
+	}
+
+	if err := lintPipeline(name, manifest); err != nil {
+		return nil, err
+	}
+
+	return util.ParseYaml(manifest)
+}
+
+// applyPipeline validates and lints a rendered pipeline manifest, then creates it on the cluster
+func applyPipeline(name string, manifest []byte) (*unstructured.Unstructured, error) {
+	obj, err := preparePipeline(name, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pipelineGvro.CreateObject(obj, dynamicClient, log); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// lintPipeline prints the lint issues of a pipeline manifest, returning an error if any of them is an error
+func lintPipeline(name string, manifest []byte) error {
+	obj, err := util.ParseYaml(manifest)
+	if err != nil {
+		return err
+	}
+
+	g, err := pipeline.ParseGraph(obj)
+	if err != nil {
+		return fmt.Errorf("unable to lint %s: %w", name, err)
+	}
+
+	numaflowVersion, err := util.NumaflowVersion(kubeClient)
+	if err != nil {
+		log.Warn("Unable to determine numaflow version", zap.Error(err))
+	}
+
+	issues := pipeline.Lint(g, pipeline.LintOptions{NumaflowVersion: numaflowVersion})
+	for _, issue := range issues {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, issue)
+	}
+
+	if pipeline.HasErrors(issues) {
+		return fmt.Errorf("%s failed pipeline lint", name)
+	}
+
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(pipelineCmd)
+	pipelineCmd.AddCommand(pipelineLintCmd)
+	pipelineCmd.AddCommand(pipelineListCmd)
+	pipelineCmd.AddCommand(pipelineApplyCmd)
 
-	// TODO: add path flag so that users can specify their own custom testing pipelines
+	pipelineCmd.Flags().StringVarP(&PipelineFile, "file", "f", util.DefaultPipelineFile, "Pipeline manifest, or directory of manifests, to apply")
+	pipelineCmd.PersistentFlags().StringArrayVar(&PipelineValues, "set", nil, "Template value in the form key=value, can be repeated")
 }
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/ayildirim21/numaflow-perfman/portforward"
+)
+
+// daemonPollInterval is how often the state of the daemon is checked while it starts or stops
+const daemonPollInterval = 500 * time.Millisecond
+
+var PfStartTimeout time.Duration
+var PfStopTimeout time.Duration
+
+var portforwardStartCmd = &cobra.Command{
+	Use:   "start [TARGET...]",
+	Short: "Start port forwarding in the background",
+	Long: "The start command runs the selected port forwards in a detached process that outlives the terminal. " +
+		"It returns once every forward is ready and its service responds, with the process id and local ports written to the state file",
+	Args: validateTargetArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !PfPrometheus && !PfGrafana && len(args) == 0 {
+			return errors.New("no service selected to port forward")
+		}
+
+		statePath, err := daemonStatePath()
+		if err != nil {
+			return err
+		}
+
+		state, err := portforward.ReadState(statePath)
+		switch {
+		case err == nil && state.Running():
+			return fmt.Errorf("a port forward daemon is already running with pid %d", state.PID)
+		case err == nil:
+			log.Warn("Removing state of a port forward daemon that is no longer running", zap.Int("pid", state.PID))
+			if err := portforward.RemoveState(statePath); err != nil {
+				return err
+			}
+		case !errors.Is(err, portforward.ErrNoState):
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(statePath), 0o755); err != nil {
+			return fmt.Errorf("failed to create state directory: %w", err)
+		}
+		logPath := portforward.LogPath(statePath)
+		logFile, err := os.Create(logPath)
+		if err != nil {
+			return fmt.Errorf("failed to create port forward log: %w", err)
+		}
+		defer logFile.Close()
+
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("unable to locate the perfman executable: %w", err)
+		}
+
+		daemon := exec.Command(executable, portforwardArgs(statePath, args)...)
+		daemon.Stdout, daemon.Stderr = logFile, logFile
+		daemon.SysProcAttr = portforward.DetachedProcAttr()
+		if err := daemon.Start(); err != nil {
+			return fmt.Errorf("failed to start port forward daemon: %w", err)
+		}
+
+		exited := make(chan error, 1)
+		go func() { exited <- daemon.Wait() }()
+
+		timeout := time.After(PfStartTimeout)
+		ticker := time.NewTicker(daemonPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case err := <-exited:
+				return fmt.Errorf("port forward daemon exited (%v), see %s", err, logPath)
+			case <-timeout:
+				_ = daemon.Process.Kill()
+				return fmt.Errorf("port forwards were not ready within %s, see %s", PfStartTimeout, logPath)
+			case <-ticker.C:
+			}
+
+			state, err := portforward.ReadState(statePath)
+			if err == nil && state.PID == daemon.Process.Pid {
+				return writeDaemonStatus(os.Stdout, state)
+			}
+		}
+	},
+}
+
+var portforwardStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the port forwards running in the background",
+	Long:  "The status command prints the process id and the forwards of the port forward daemon. It fails if no daemon is running",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		statePath, err := daemonStatePath()
+		if err != nil {
+			return err
+		}
+
+		state, err := portforward.ReadState(statePath)
+		if err != nil {
+			return err
+		}
+		if !state.Running() {
+			return fmt.Errorf("port forward daemon with pid %d is no longer running, see %s", state.PID, state.LogFile)
+		}
+
+		return writeDaemonStatus(os.Stdout, state)
+	},
+}
+
+var portforwardStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the port forwards running in the background",
+	Long:  "The stop command terminates the port forward daemon, waits for it to close its forwards and removes its state file",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		statePath, err := daemonStatePath()
+		if err != nil {
+			return err
+		}
+
+		state, err := portforward.ReadState(statePath)
+		if err != nil {
+			return err
+		}
+
+		if state.Running() {
+			process, err := os.FindProcess(state.PID)
+			if err != nil {
+				return fmt.Errorf("unable to find port forward daemon with pid %d: %w", state.PID, err)
+			}
+			if err := process.Signal(syscall.SIGTERM); err != nil {
+				return fmt.Errorf("failed to terminate port forward daemon with pid %d: %w", state.PID, err)
+			}
+
+			deadline := time.Now().Add(PfStopTimeout)
+			for state.Running() {
+				if time.Now().After(deadline) {
+					return fmt.Errorf("port forward daemon with pid %d did not stop within %s", state.PID, PfStopTimeout)
+				}
+				time.Sleep(daemonPollInterval)
+			}
+			log.Info("Stopped port forward daemon", zap.Int("pid", state.PID))
+		} else {
+			log.Warn("Port forward daemon is no longer running, removing its state", zap.Int("pid", state.PID))
+		}
+
+		// The daemon removes its state file on a clean exit, this covers the rest
+		return portforward.RemoveState(statePath)
+	},
+}
+
+// daemonStatePath returns the state file given by --state-file, or the default one
+func daemonStatePath() (string, error) {
+	if PfStateFile != "" {
+		return PfStateFile, nil
+	}
+
+	return portforward.DefaultStatePath()
+}
+
+// forwardedURL returns the local url of a service forwarded by a running portforward session, if there is one
+func forwardedURL(name string) (string, bool) {
+	statePath, err := daemonStatePath()
+	if err != nil {
+		return "", false
+	}
+
+	state, err := portforward.ReadState(statePath)
+	if err != nil || !state.Running() {
+		return "", false
+	}
+
+	if s := state.Service(name); s != nil {
+		return s.URL, true
+	}
+
+	return "", false
+}
+
+// portforwardArgs returns the arguments that run the selected forwards and services in the foreground, writing their state to statePath
+func portforwardArgs(statePath string, services []string) []string {
+	args := []string{"portforward", "--state-file", statePath}
+	if PfPrometheus {
+		args = append(args, "--prometheus", "--prometheus-port", strconv.Itoa(PfPrometheusPort))
+	}
+	if PfGrafana {
+		args = append(args, "--grafana", "--grafana-port", strconv.Itoa(PfGrafanaPort))
+	}
+
+	args = append(args, fmt.Sprintf("--health-check=%t", PfHealthCheck),
+		"--health-interval", PfHealthInterval.String(), "--health-timeout", PfHealthTimeout.String())
+
+	return append(args, services...)
+}
+
+// writeDaemonStatus prints the process of the daemon followed by one row per forward, probing the health of each
+func writeDaemonStatus(out io.Writer, state *portforward.State) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "pid\t%d\n", state.PID)
+	fmt.Fprintf(w, "started\t%s\n", state.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(w, "log\t%s\n", state.LogFile)
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "service\tpod\tlocal url\thealth")
+	for _, s := range state.Services {
+		health := "-"
+		if s.HealthURL != "" {
+			health = "healthy"
+			if err := portforward.Probe(context.Background(), s.HealthURL); err != nil {
+				health = fmt.Sprintf("unhealthy: %v", err)
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Name, s.Pod, s.URL, health)
+	}
+
+	return w.Flush()
+}
+
+func init() {
+	portforwardCmd.AddCommand(portforwardStartCmd)
+	portforwardCmd.AddCommand(portforwardStatusCmd)
+	portforwardCmd.AddCommand(portforwardStopCmd)
+
+	portforwardStartCmd.Flags().DurationVar(&PfStartTimeout, "timeout", 3*time.Minute, "How long to wait for every forward to be ready, including health checks")
+	portforwardStopCmd.Flags().DurationVar(&PfStopTimeout, "timeout", 10*time.Second, "How long to wait for the daemon to exit")
+}
@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericiooptions"
@@ -17,20 +23,21 @@ import (
 
 var PfPrometheus bool
 var PfGrafana bool
+var PfPrometheusPort int
+var PfGrafanaPort int
+var PfHealthCheck bool
+var PfHealthInterval time.Duration
+var PfHealthTimeout time.Duration
 
 // portforwardCmd represents the pf command
 var portforwardCmd = &cobra.Command{
-	Use:   "portforward",
+	Use:   "portforward [TARGET...]",
 	Short: "Port forward services",
-	Long:  "Port forward services",
-	Args: func(cmd *cobra.Command, args []string) error {
-		nonFlagArgs := cmd.Flags().Args()
-		if len(nonFlagArgs) > 0 {
-			return errors.New("this command doesn't accept args")
-		}
-
-		return nil
-	},
+	Long: "Port forward services. Every selected service is forwarded concurrently until the command is interrupted. " +
+		"Besides Prometheus and Grafana, targets can be given as arguments: svc/NAME:PORT for any service in the perfman namespace, " +
+		"numaflow-ui for the Numaflow UI server, daemon PIPELINE for a pipeline's daemon service and vertex PIPELINE/VERTEX for " +
+		"the metrics and pprof port of a vertex pod. Targets are forwarded to the port they are served on, or to a free port if it is taken",
+	Args: validateTargetArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 
 		// stream is used to tell the port forwarder where to place its output, and where to expect input if needed
@@ -40,102 +47,172 @@ var portforwardCmd = &cobra.Command{
 			ErrOut: os.Stderr,
 		}
 
-		if cmd.Flag("prometheus").Changed && cmd.Flag("grafana").Changed {
-			return errors.New("only one service can be port forwarded at a time")
-		}
+		var forwards []*portforward.Forward
 
 		// Port forward prometheus operator so that it can be used as a source in the Grafana dashboard
-		if cmd.Flag("prometheus").Changed {
-			serviceName := util.PrometheusPFServiceName
-			podName, err := portforward.GetPodFromService(kubeClient, util.PerfmanNamespace, serviceName)
+		if PfPrometheus {
+			forward, err := newForward("prometheus", portforward.Target{
+				Kind: portforward.TargetService,
+				Name: util.PrometheusPFServiceName,
+				Port: util.PrometheusServicePort,
+			}, PfPrometheusPort, portforward.PrometheusHealthPath, stream)
 			if err != nil {
-				return fmt.Errorf("unable to find a pod for the service: %w", err)
+				return err
 			}
-
-			var prometheusWg sync.WaitGroup
-			prometheusWg.Add(1)
-			prometheusStopCh := make(chan struct{}, 1)
-			prometheusReadyCh := make(chan struct{})
-
-			prometheusPf := portforward.APodRequest{
-				RestConfig: config,
-				Pod: v1.Pod{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      podName,
-						Namespace: util.PerfmanNamespace,
-					},
-				},
-				LocalPort: 9090,
-				PodPort:   9090,
-				Streams:   stream,
-				StopCh:    prometheusStopCh,
-				ReadyCh:   prometheusReadyCh,
-			}
-
-			portforward.WaitForTermination(prometheusStopCh, &prometheusWg)
-
-			go func() {
-				err := prometheusPf.PortForwardAPod()
-				if err != nil {
-					panic(err)
-				}
-			}()
-
-			<-prometheusPf.ReadyCh
-
-			prometheusWg.Wait()
+			forwards = append(forwards, forward)
 		}
 
 		// Port forward Grafana
-		if cmd.Flag("grafana").Changed {
-			serviceName := util.GrafanaPFServiceName
-			podName, err := portforward.GetPodFromService(kubeClient, util.PerfmanNamespace, serviceName)
+		if PfGrafana {
+			forward, err := newForward("grafana", portforward.Target{
+				Kind: portforward.TargetService,
+				Name: util.GrafanaPFServiceName,
+				Port: util.GrafanaServicePort,
+			}, PfGrafanaPort, portforward.GrafanaHealthPath, stream)
 			if err != nil {
-				return fmt.Errorf("unable to find a pod for the service: %w", err)
+				return err
 			}
+			forwards = append(forwards, forward)
+		}
 
-			var grafanaWg sync.WaitGroup
-			grafanaWg.Add(1)
-			grafanaStopCh := make(chan struct{}, 1)
-			grafanaReadyCh := make(chan struct{})
-
-			grafanaPf := portforward.APodRequest{
-				RestConfig: config,
-				Pod: v1.Pod{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      podName,
-						Namespace: util.PerfmanNamespace,
-					},
-				},
-				LocalPort: 3000,
-				PodPort:   3000,
-				Streams:   stream,
-				StopCh:    grafanaStopCh,
-				ReadyCh:   grafanaReadyCh,
+		targets, err := portforward.ParseTargets(args)
+		if err != nil {
+			return err
+		}
+		used := map[int]bool{PfPrometheusPort: PfPrometheus, PfGrafanaPort: PfGrafana}
+		for _, target := range targets {
+			localPort := target.DefaultLocalPort()
+			if used[localPort] || !portforward.PortAvailable(localPort) {
+				localPort = 0
+			}
+			forward, err := newForward(target.String(), target, localPort, target.HealthPath(), stream)
+			if err != nil {
+				return err
 			}
+			used[forward.Request.LocalPort] = true
+			forwards = append(forwards, forward)
+		}
 
-			portforward.WaitForTermination(grafanaStopCh, &grafanaWg)
+		if len(forwards) == 0 {
+			return errors.New("no service selected to port forward")
+		}
 
-			go func() {
-				err := grafanaPf.PortForwardAPod()
-				if err != nil {
-					panic(err)
-				}
-			}()
+		statePath, err := daemonStatePath()
+		if err != nil {
+			return err
+		}
+		if state, err := portforward.ReadState(statePath); err == nil && state.Running() {
+			return fmt.Errorf("port forwards are already running with pid %d, stop them with portforward stop", state.PID)
+		}
 
-			<-grafanaPf.ReadyCh
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
-			grafanaWg.Wait()
+		// The state file is only written once every forward is ready, a daemon waiting on it knows the forwards are usable
+		defer func() {
+			if state, err := portforward.ReadState(statePath); err == nil && state.PID == os.Getpid() {
+				if err := portforward.RemoveState(statePath); err != nil {
+					log.Warn("Unable to remove port forward state", zap.Error(err))
+				}
+			}
+		}()
+
+		// ready is called again after every reconnect, only the state file, which names the pods, needs updating then
+		var summaryOnce sync.Once
+		startedAt := time.Now()
+		err = portforward.RunSession(forwards, ctx.Done(), log, func() {
+			summaryOnce.Do(func() {
+				w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "service\tlocal url")
+				for _, f := range forwards {
+					fmt.Fprintf(w, "%s\t%s\n", f.Name, f.URL())
+				}
+				_ = w.Flush()
+			})
+
+			state := portforward.NewState(forwards, portforward.LogPath(statePath))
+			state.StartedAt = startedAt
+			if err := portforward.WriteState(statePath, state); err != nil {
+				log.Error("Unable to write port forward state", zap.Error(err))
+				stop()
+			}
+		})
+		if ctx.Err() != nil {
+			fmt.Println("Terminating connections...")
 		}
 
-		return nil
+		return err
 	},
 }
 
+// newForward returns a forward of localPort to a ready pod of the target. A localPort of zero picks a free port.
+// Unless health checks are disabled, the service is probed on healthPath if it is not empty.
+func newForward(name string, target portforward.Target, localPort int, healthPath string, stream genericiooptions.IOStreams) (*portforward.Forward, error) {
+	if localPort == 0 {
+		port, err := portforward.FreePort()
+		if err != nil {
+			return nil, err
+		}
+		localPort = port
+	}
+
+	resolve := func() (portforward.Endpoint, error) {
+		return target.Resolve(context.TODO(), kubeClient, util.PerfmanNamespace)
+	}
+
+	endpoint, err := resolve()
+	if err != nil {
+		return nil, fmt.Errorf("unable to find a pod for %s: %w", name, err)
+	}
+
+	serviceName, err := target.Service(context.TODO(), kubeClient, util.PerfmanNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if !PfHealthCheck {
+		healthPath = ""
+	}
+
+	return &portforward.Forward{
+		Name:           name,
+		Service:        serviceName,
+		Scheme:         target.Scheme(),
+		Resolve:        resolve,
+		HealthPath:     healthPath,
+		HealthInterval: PfHealthInterval,
+		HealthTimeout:  PfHealthTimeout,
+		Request: &portforward.APodRequest{
+			RestConfig: config,
+			Pod: v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      endpoint.Pod,
+					Namespace: target.Namespace(util.PerfmanNamespace),
+				},
+			},
+			LocalPort: localPort,
+			PodPort:   endpoint.Port,
+			Streams:   stream,
+		},
+	}, nil
+}
+
+// validateTargetArgs checks that the arguments are a list of targets
+func validateTargetArgs(cmd *cobra.Command, args []string) error {
+	_, err := portforward.ParseTargets(args)
+	return err
+}
+
 func init() {
 	rootCmd.AddCommand(portforwardCmd)
 
-	portforwardCmd.Flags().BoolVarP(&PfPrometheus, "prometheus", "p", false, "Port forward prometheus operator to localhost:9090")
-	portforwardCmd.Flags().BoolVarP(&PfGrafana, "grafana", "g", false, "Port forward grafana to localhost:3000")
-
+	for _, c := range []*cobra.Command{portforwardCmd, portforwardStartCmd} {
+		c.Flags().BoolVarP(&PfPrometheus, "prometheus", "p", false, "Port forward prometheus operator to localhost, on --prometheus-port")
+		c.Flags().BoolVarP(&PfGrafana, "grafana", "g", false, "Port forward grafana to localhost, on --grafana-port")
+		c.Flags().IntVar(&PfPrometheusPort, "prometheus-port", 9090, "Local port prometheus is forwarded to, 0 picks a free one")
+		c.Flags().IntVar(&PfGrafanaPort, "grafana-port", 3000, "Local port grafana is forwarded to, 0 picks a free one")
+		c.Flags().BoolVar(&PfHealthCheck, "health-check", true, "Probe the health endpoint of every service that has one, forwards are only ready once it responds")
+		c.Flags().DurationVar(&PfHealthInterval, "health-interval", 30*time.Second, "How often forwarded services are probed once they are ready")
+		c.Flags().DurationVar(&PfHealthTimeout, "health-timeout", 2*time.Minute, "How long a forwarded service may take to respond to its first probe")
+	}
 }
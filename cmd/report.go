@@ -1,43 +1,65 @@
 package cmd
 
 import (
-	"encoding/base64"
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 
+	"github.com/ayildirim21/numaflow-perfman/benchmark"
+	"github.com/ayildirim21/numaflow-perfman/portforward"
 	"github.com/ayildirim21/numaflow-perfman/report"
 	"github.com/ayildirim21/numaflow-perfman/util"
 )
 
+var ReportRun string
+var ReportGrafanaURL string
+var ReportGrafanaToken string
+var ReportNumaflowCommit string
+var ReportKeepHistory bool
+
 var reportCmd = &cobra.Command{
 	Use:   "report",
 	Short: "Generate reporting dashboard snapshot url",
-	Long:  "The report command generates a url for user to open and see the snapshot of the reporting dashboard",
+	Long: "The report command saves the reporting dashboard under a stable uid in the perfman folder, overwriting the one " +
+		"an earlier report saved, and generates a url for user to open and see the snapshot of it",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		grafanaURL, err := reportGrafanaURL()
+		if err != nil {
+			return err
+		}
+
 		// TODO: can all be moved to viper configuration
-		grafanaURL := "http://localhost:3000"
 		filePath := "default/dashboard-template.json" // the path to default dashboard template file.
-		username := "admin"
-		password := util.GrafanaPassword
+		var auth report.Auth = report.BasicAuth{Username: "admin", Password: util.GrafanaPassword}
+		if ReportGrafanaToken != "" {
+			auth = report.TokenAuth{Token: ReportGrafanaToken}
+		}
+		grafana := report.NewGrafanaClient(grafanaURL, auth)
 
-		// Prepare for authentication
-		auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
 		// Create the Prometheus data source
-		dsId, err := report.CreateGrafanaDataSource(grafanaURL, auth)
-		if err != nil {
-			if strings.Contains(err.Error(), "data source with the same name already exists") {
-				log.Warn("Prometheus data source has already been configured.")
-				// Attempt to fetch the UID of the existing data source
-				dsId, err = report.FetchGrafanaDataSourceUID(grafanaURL, auth)
-				if err != nil {
-					return fmt.Errorf("error fetching existing data source UID: %w", err)
-				}
-			} else {
-				return fmt.Errorf("error creating data source: %w", err)
+		dsId, err := grafana.CreateDataSource(ctx)
+		switch {
+		case errors.Is(err, report.ErrAlreadyExists):
+			log.Warn("Prometheus data source has already been configured.")
+			// Attempt to fetch the UID of the existing data source
+			dsId, err = grafana.FetchDataSourceUID(ctx, report.DataSourceName)
+			if err != nil {
+				return fmt.Errorf("error fetching existing data source UID: %w", err)
 			}
+		case errors.Is(err, report.ErrUnauthorized):
+			return fmt.Errorf("grafana rejected the credentials, check the password or --grafana-token: %w", err)
+		case err != nil:
+			return fmt.Errorf("error creating data source: %w", err)
 		}
 
 		// Read dashboard template from JSON file
@@ -49,22 +71,65 @@ var reportCmd = &cobra.Command{
 		// Configure the dashboard template to read from the data source created above
 		dashboardData = []byte(strings.Replace(string(dashboardData), "prometheus-datasource-uid-placeholder", dsId, -1))
 
-		// Create Dashboard
-		// TODO - handle case when the dashboard already exists.
-		// (we should move data source and dashboard creation into setup and let report to only generate snapshot.)
-		resp, err := report.CreateDashboard(grafanaURL, auth, dashboardData)
+		folder, err := grafana.EnsureFolder(ctx, report.FolderUID, report.FolderTitle)
+		if err != nil {
+			return fmt.Errorf("error creating dashboard folder: %w", err)
+		}
+
+		// Tag the dashboard with what is under test, so it can be told apart from those of earlier versions
+		tags, message := []string{"perfman"}, "perfman report"
+		if version, err := util.NumaflowVersion(kubeClient); err != nil {
+			log.Warn("Unable to determine the numaflow version, the dashboard is not tagged with it", zap.Error(err))
+		} else {
+			tags = append(tags, "numaflow:"+version)
+			message += ", numaflow " + version
+		}
+		if ReportNumaflowCommit != "" {
+			tags = append(tags, "numaflow-commit:"+ReportNumaflowCommit)
+			message += ", commit " + ReportNumaflowCommit
+		}
+		if util.CommitSHA != "" {
+			tags = append(tags, "perfman-commit:"+util.CommitSHA)
+		}
+
+		dashboardData, err = report.PrepareDashboard(dashboardData, report.DashboardOptions{
+			UID:       report.DashboardUID,
+			FolderUID: folder.UID,
+			Tags:      tags,
+			Message:   message,
+		})
 		if err != nil {
 			return err
 		}
 
+		// Create the dashboard, or overwrite the one an earlier report created
+		resp, err := grafana.UpsertDashboard(ctx, dashboardData, ReportKeepHistory)
+		if err != nil {
+			return fmt.Errorf("error saving dashboard: %w", err)
+		}
+		log.Info("Saved dashboard", zap.String("uid", resp.UID), zap.Int("version", resp.Version), zap.String("folder", folder.Title))
+
 		// Fetch the dashboard
-		dashboardData, err = report.FetchDashboard(grafanaURL, auth, resp.UID)
+		dashboardData, err = grafana.FetchDashboard(ctx, resp.UID)
 		if err != nil {
 			return err
 		}
 
+		// Pin the snapshot to the measurement window of a benchmark run
+		if ReportRun != "" {
+			rec, err := benchmark.LoadRunRecord(ReportRun)
+			if err != nil {
+				return err
+			}
+
+			dashboardData, err = report.SetTimeWindow(dashboardData, rec.WindowStart, rec.WindowEnd, rec.Pipeline)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Create a snapshot
-		reportUrl, err := report.CreateSnapshot(grafanaURL, auth, dashboardData)
+		reportUrl, err := grafana.CreateSnapshot(ctx, dashboardData)
 		if err != nil {
 			return err
 		}
@@ -74,6 +139,21 @@ var reportCmd = &cobra.Command{
 	},
 }
 
+// reportGrafanaURL returns the url given by --grafana-url, or by serviceURL if it is not set
+func reportGrafanaURL() (string, error) {
+	if ReportGrafanaURL != "" {
+		return ReportGrafanaURL, nil
+	}
+
+	return serviceURL("grafana", util.GrafanaPFServiceName, util.GrafanaServicePort, portforward.GrafanaHealthPath)
+}
+
 func init() {
 	rootCmd.AddCommand(reportCmd)
+
+	reportCmd.Flags().StringVar(&ReportRun, "run", "", "Run record whose measurement window the snapshot should cover")
+	reportCmd.Flags().StringVar(&ReportGrafanaURL, "grafana-url", "", "URL of the Grafana server, defaults to a running portforward session or an in-process forward")
+	reportCmd.Flags().StringVar(&ReportNumaflowCommit, "numaflow-commit", "", "Numaflow commit under test, the dashboard is tagged with it")
+	reportCmd.Flags().BoolVar(&ReportKeepHistory, "keep-history", false, "Copy the previous dashboard to its own versioned uid before overwriting it with a different layout")
+	reportCmd.Flags().StringVar(&ReportGrafanaToken, "grafana-token", "", "Service account token to authenticate to Grafana with instead of the admin password")
 }
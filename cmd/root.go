@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -9,13 +12,23 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
+	"github.com/ayildirim21/numaflow-perfman/metrics"
+	"github.com/ayildirim21/numaflow-perfman/portforward"
 	"github.com/ayildirim21/numaflow-perfman/util"
+	"github.com/ayildirim21/numaflow-perfman/validate"
 )
 
 var config *rest.Config
 var kubeClient *kubernetes.Clientset
 var dynamicClient *dynamic.DynamicClient
 var log *zap.Logger
+var schemaFetcher *validate.SchemaFetcher
+
+var PrometheusURL string
+var PfStateFile string
+
+// tunnelTimeout bounds how long an in-process port forward may take to be ready
+const tunnelTimeout = 30 * time.Second
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -24,13 +37,64 @@ var rootCmd = &cobra.Command{
 	Long:  "Perfman is a command line utility for performance testing changes to the numaflow platform",
 }
 
+// tunnels are the in-process port forwards opened by the command, by service name
+var tunnels = map[string]*portforward.Tunnel{}
+
 // Execute adds all child commands to the root command and sets flags appropriately
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	closeTunnels()
+	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// newPrometheusClient returns a client for the Prometheus server given by --prometheus-url, or by serviceURL if it is not set
+func newPrometheusClient() (*metrics.PrometheusClient, error) {
+	if PrometheusURL != "" {
+		return metrics.NewPrometheusClient(PrometheusURL), nil
+	}
+
+	url, err := serviceURL("prometheus", util.PrometheusPFServiceName, util.PrometheusServicePort, portforward.PrometheusHealthPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return metrics.NewPrometheusClient(url), nil
+}
+
+// serviceURL returns the local url of the service as forwarded by a running portforward session. Without one, an
+// in-process forward is opened for the rest of the command, it is shared by every caller asking for the same service.
+func serviceURL(name string, serviceName string, servicePort int, healthPath string) (string, error) {
+	if url, ok := forwardedURL(name); ok {
+		return url, nil
+	}
+
+	if t, found := tunnels[serviceName]; found {
+		return t.URL(), nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tunnelTimeout)
+	defer cancel()
+
+	t, err := portforward.OpenTunnel(ctx, config, kubeClient, util.PerfmanNamespace, serviceName, servicePort, healthPath, log)
+	if err != nil {
+		return "", fmt.Errorf("unable to port forward %s, run portforward start or pass its url: %w", name, err)
+	}
+	tunnels[serviceName] = t
+	log.Info("Opened port forward", zap.String("service", serviceName), zap.String("url", t.URL()))
+
+	return t.URL(), nil
+}
+
+func closeTunnels() {
+	for serviceName, t := range tunnels {
+		if err := t.Close(); err != nil {
+			log.Warn("Unable to close port forward", zap.String("service", serviceName), zap.Error(err))
+		}
+	}
+}
+
 func init() {
 	var err error
 
@@ -49,5 +113,12 @@ func init() {
 		panic(err)
 	}
 
+	schemaFetcher = &validate.SchemaFetcher{DynamicClient: dynamicClient}
+
 	log = util.CreateLogger()
+
+	rootCmd.PersistentFlags().StringVar(&PrometheusURL, "prometheus-url", "",
+		"URL of the Prometheus server metrics are queried from, defaults to a running portforward session or an in-process forward")
+	rootCmd.PersistentFlags().StringVar(&PfStateFile, "state-file", "",
+		"File port forwards publish their local ports in, and other commands read them from (default ~/.perfman/portforward.json)")
 }
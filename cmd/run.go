@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/ayildirim21/numaflow-perfman/benchmark"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+var RunFile string
+var RunScenario string
+var RunValues []string
+var RunDuration time.Duration
+var RunWarmup time.Duration
+var RunOutputDir string
+var RunLoadProfile string
+var RunChaosPlan string
+var RunAutoscaling bool
+var RunAutoscalingInterval time.Duration
+var RunPendingThreshold float64
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run a time-boxed benchmark of a pipeline",
+	Long: "The run command applies a pipeline, waits until it is running, keeps it running through a warmup and a " +
+		"measurement window, then deletes it. The exact measurement window is saved as a run record for the report command",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		values, err := pipeline.ParseValues(RunValues)
+		if err != nil {
+			return err
+		}
+
+		name, manifest, err := renderPipeline(RunFile, RunScenario, values)
+		if err != nil {
+			return err
+		}
+
+		obj, err := preparePipeline(name, manifest)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		runner := benchmark.Runner{
+			DynamicClient: dynamicClient,
+			KubeClient:    kubeClient,
+			Namespace:     util.PerfmanNamespace,
+			Log:           log,
+		}
+
+		opts := benchmark.RunOptions{Warmup: RunWarmup, Duration: RunDuration}
+
+		if RunLoadProfile != "" {
+			profile, err := benchmark.ReadLoadProfile(RunLoadProfile)
+			if err != nil {
+				return err
+			}
+
+			driver, err := benchmark.NewLoadDriver(dynamicClient, util.PerfmanNamespace, obj, profile, log)
+			if err != nil {
+				return err
+			}
+			opts.Observers = append(opts.Observers, driver.Observe)
+		}
+
+		var chaos *benchmark.ChaosScheduler
+		if RunChaosPlan != "" {
+			plan, err := benchmark.ReadChaosPlan(RunChaosPlan)
+			if err != nil {
+				return err
+			}
+
+			prom, err := newPrometheusClient()
+			if err != nil {
+				return err
+			}
+
+			chaos, err = benchmark.NewChaosScheduler(kubeClient, prom, util.PerfmanNamespace, obj, plan, log)
+			if err != nil {
+				return err
+			}
+			opts.Observers = append(opts.Observers, chaos.Observe)
+		}
+
+		var autoscaling *benchmark.AutoscalingObserver
+		if RunAutoscaling {
+			prom, err := newPrometheusClient()
+			if err != nil {
+				return err
+			}
+
+			autoscaling = benchmark.NewAutoscalingObserver(dynamicClient, prom, util.PerfmanNamespace, RunAutoscalingInterval, log)
+			opts.Observers = append(opts.Observers, autoscaling.Observe)
+		}
+
+		rec, err := runner.Run(ctx, obj, opts)
+
+		if chaos != nil {
+			if err := benchmark.WriteChaosTable(os.Stdout, chaos.Results()); err != nil {
+				return err
+			}
+		}
+
+		if autoscaling != nil {
+			// The observer only starts sampling once the measurement window opens
+			if summary := autoscaling.Summary(RunPendingThreshold); summary.Pipeline != "" {
+				if err := benchmark.WriteAutoscalingTable(os.Stdout, summary); err != nil {
+					return err
+				}
+
+				path, err := summary.Save(RunOutputDir)
+				if err != nil {
+					return err
+				}
+				log.Info("Saved autoscaling summary", zap.String("path", path))
+			}
+		}
+
+		// A run whose teardown failed still measured a full window, keep its record
+		if rec != nil {
+			path, err := rec.Save(RunOutputDir)
+			if err != nil {
+				return err
+			}
+			log.Info("Saved run record", zap.String("path", path))
+		}
+
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return errors.New("run interrupted")
+			}
+			return fmt.Errorf("benchmark run failed: %w", err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().StringVarP(&RunFile, "file", "f", util.DefaultPipelineFile, "Pipeline manifest to benchmark")
+	runCmd.Flags().StringVar(&RunScenario, "scenario", "", "Built-in pipeline scenario to benchmark instead of --file")
+	runCmd.Flags().StringArrayVar(&RunValues, "set", nil, "Template value in the form key=value, can be repeated")
+	runCmd.Flags().DurationVar(&RunDuration, "duration", 10*time.Minute, "Length of the measurement window")
+	runCmd.Flags().DurationVar(&RunWarmup, "warmup", 2*time.Minute, "How long the pipeline runs before the measurement window opens")
+	runCmd.Flags().StringVar(&RunLoadProfile, "load-profile", "", "Load profile driving the generator during the measurement window")
+	runCmd.Flags().StringVar(&RunChaosPlan, "chaos", "", "Chaos plan of faults to inject during the measurement window")
+	runCmd.Flags().BoolVar(&RunAutoscaling, "observe-autoscaling", false, "Record the replica timeline of every vertex and report how the autoscaler reacted")
+	runCmd.Flags().DurationVar(&RunAutoscalingInterval, "autoscaling-interval", 10*time.Second, "Time between samples of the replica timeline")
+	runCmd.Flags().Float64Var(&RunPendingThreshold, "pending-threshold", 100, "Pending messages above which a vertex counts as backlogged when timing scaling reactions")
+	runCmd.Flags().StringVarP(&RunOutputDir, "output-dir", "o", util.RunRecordDir, "Directory run records are written to")
+}
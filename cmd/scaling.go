@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/ayildirim21/numaflow-perfman/benchmark"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+var ScalingFile string
+var ScalingScenario string
+var ScalingValues []string
+var ScalingVertex string
+var ScalingMaxReplicas int64
+var ScalingReadyTimeout time.Duration
+var ScalingSettle time.Duration
+var ScalingHold time.Duration
+var ScalingOutputDir string
+
+// scalingCmd represents the scaling command
+var scalingCmd = &cobra.Command{
+	Use:   "scaling",
+	Short: "Measure how the throughput of a vertex scales with its replicas",
+	Long: "The scaling command runs a pipeline and pins one vertex at 1, 2, 4, ... up to --max-replicas replicas through " +
+		"its scale.min and scale.max, measuring the vertex and sink throughput at each count. Speedup and efficiency are " +
+		"reported relative to one replica, showing where partitions, the ISB or the UDF stop horizontal scaling",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if ScalingVertex == "" {
+			return errors.New("--vertex is required")
+		}
+		if ScalingMaxReplicas < 1 {
+			return errors.New("--max-replicas must be at least 1")
+		}
+
+		values, err := pipeline.ParseValues(ScalingValues)
+		if err != nil {
+			return err
+		}
+
+		name, manifest, err := renderPipeline(ScalingFile, ScalingScenario, values)
+		if err != nil {
+			return err
+		}
+
+		obj, err := preparePipeline(name, manifest)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		prom, err := newPrometheusClient()
+		if err != nil {
+			return err
+		}
+
+		runner := benchmark.Runner{
+			DynamicClient: dynamicClient,
+			KubeClient:    kubeClient,
+			Namespace:     util.PerfmanNamespace,
+			Log:           log,
+		}
+
+		result, err := runner.SweepScaling(ctx, obj, prom, benchmark.ScalingOptions{
+			Vertex:       ScalingVertex,
+			Replicas:     benchmark.ReplicaSteps(ScalingMaxReplicas),
+			ReadyTimeout: ScalingReadyTimeout,
+			Settle:       ScalingSettle,
+			Hold:         ScalingHold,
+		})
+
+		if result != nil {
+			if err := benchmark.WriteScalingTable(os.Stdout, result); err != nil {
+				return err
+			}
+
+			path, err := result.Save(ScalingOutputDir)
+			if err != nil {
+				return err
+			}
+			log.Info("Saved scaling result", zap.String("path", path))
+
+			path, err = result.Record.Save(ScalingOutputDir)
+			if err != nil {
+				return err
+			}
+			log.Info("Saved run record", zap.String("path", path))
+		}
+
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return errors.New("scaling sweep interrupted")
+			}
+			return fmt.Errorf("scaling sweep failed: %w", err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(scalingCmd)
+
+	scalingCmd.Flags().StringVarP(&ScalingFile, "file", "f", util.DefaultPipelineFile, "Pipeline manifest to run")
+	scalingCmd.Flags().StringVar(&ScalingScenario, "scenario", "", "Built-in pipeline scenario to run instead of --file")
+	scalingCmd.Flags().StringArrayVar(&ScalingValues, "set", nil, "Template value in the form key=value, can be repeated")
+	scalingCmd.Flags().StringVar(&ScalingVertex, "vertex", "", "Vertex whose replicas are swept")
+	scalingCmd.Flags().Int64Var(&ScalingMaxReplicas, "max-replicas", 8, "Largest replica count, counts double from 1 up to it")
+	scalingCmd.Flags().DurationVar(&ScalingReadyTimeout, "ready-timeout", 5*time.Minute, "How long the vertex may take until all pods of a replica count are ready")
+	scalingCmd.Flags().DurationVar(&ScalingSettle, "settle", time.Minute, "How long each replica count runs before it is measured")
+	scalingCmd.Flags().DurationVar(&ScalingHold, "hold", 2*time.Minute, "How long each replica count is measured for")
+	scalingCmd.Flags().StringVarP(&ScalingOutputDir, "output-dir", "o", util.RunRecordDir, "Directory the scaling result and run record are written to")
+}
@@ -49,6 +49,9 @@ var setupCmd = &cobra.Command{
 				Resource:  "interstepbufferservices",
 				Namespace: util.PerfmanNamespace,
 			}
+			if err := validateManifest("default/isbvc.yaml"); err != nil {
+				return err
+			}
 			if err := isbGvro.CreateResource("default/isbvc.yaml", dynamicClient, log); err != nil {
 				return fmt.Errorf("failed to create jetsream-isbvc: %w", err)
 			}
@@ -97,6 +100,17 @@ var setupCmd = &cobra.Command{
 			return fmt.Errorf("failed to create service monitor for jetstream metrics: %w", err)
 		}
 
+		// The controller's reconcile metrics are only scraped through a service perfman creates next to it
+		if err := setup.CreateControllerMetricsService(kubeClient, log); err != nil {
+			return fmt.Errorf("failed to create service for controller metrics: %w", err)
+		}
+
+		controllerSvGvro := svGvro
+		controllerSvGvro.Namespace = util.NumaflowNamespace
+		if err := controllerSvGvro.CreateResource("default/controller-metrics.yaml", dynamicClient, log); err != nil {
+			return fmt.Errorf("failed to create service monitor for controller metrics: %w", err)
+		}
+
 		return nil
 	},
 }
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/ayildirim21/numaflow-perfman/benchmark"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+var SoakFile string
+var SoakScenario string
+var SoakValues []string
+var SoakDuration time.Duration
+var SoakWarmup time.Duration
+var SoakInterval time.Duration
+var SoakMemoryTolerance float64
+var SoakMinR2 float64
+var SoakThroughputTolerance float64
+var SoakOutputDir string
+
+// soakCmd represents the soak command
+var soakCmd = &cobra.Command{
+	Use:   "soak",
+	Short: "Run a pipeline for hours and detect memory leaks and throughput drift",
+	Long: "The soak command runs a pipeline like the run command, usually for hours, sampling the memory, cpu, goroutines and " +
+		"threads of every pipeline pod and the throughput of every vertex at a fixed interval. Linear trends are fitted to the " +
+		"samples; pods whose memory grows steadily beyond --memory-tolerance and vertices whose throughput drops beyond " +
+		"--throughput-tolerance are flagged. The trends are summarized even if the soak is interrupted",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		values, err := pipeline.ParseValues(SoakValues)
+		if err != nil {
+			return err
+		}
+
+		name, manifest, err := renderPipeline(SoakFile, SoakScenario, values)
+		if err != nil {
+			return err
+		}
+
+		obj, err := preparePipeline(name, manifest)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		prom, err := newPrometheusClient()
+		if err != nil {
+			return err
+		}
+
+		runner := benchmark.Runner{
+			DynamicClient: dynamicClient,
+			KubeClient:    kubeClient,
+			Namespace:     util.PerfmanNamespace,
+			Log:           log,
+		}
+
+		sampler := benchmark.NewSoakSampler(prom, util.PerfmanNamespace, SoakInterval, log)
+		opts := benchmark.RunOptions{
+			Warmup:    SoakWarmup,
+			Duration:  SoakDuration,
+			Observers: []benchmark.Observer{sampler.Observe},
+		}
+
+		rec, runErr := runner.Run(ctx, obj, opts)
+
+		summary := sampler.Summary(benchmark.SoakOptions{
+			MemoryTolerance:     SoakMemoryTolerance,
+			MinR2:               SoakMinR2,
+			ThroughputTolerance: SoakThroughputTolerance,
+		})
+		if summary.Pipeline != "" {
+			if err := benchmark.WriteSoakSummary(os.Stdout, summary); err != nil {
+				return err
+			}
+
+			path, err := summary.Save(SoakOutputDir)
+			if err != nil {
+				return err
+			}
+			log.Info("Saved soak summary", zap.String("path", path))
+		}
+
+		// A soak whose teardown failed still sampled the full duration, keep its record
+		if rec != nil {
+			path, err := rec.Save(SoakOutputDir)
+			if err != nil {
+				return err
+			}
+			log.Info("Saved run record", zap.String("path", path))
+		}
+
+		if runErr != nil {
+			if errors.Is(runErr, context.Canceled) {
+				return errors.New("soak interrupted")
+			}
+			return fmt.Errorf("soak failed: %w", runErr)
+		}
+
+		if summary.Flagged() {
+			return errors.New("soak detected a memory leak or throughput degradation")
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(soakCmd)
+
+	soakCmd.Flags().StringVarP(&SoakFile, "file", "f", util.DefaultPipelineFile, "Pipeline manifest to soak")
+	soakCmd.Flags().StringVar(&SoakScenario, "scenario", "", "Built-in pipeline scenario to soak instead of --file")
+	soakCmd.Flags().StringArrayVar(&SoakValues, "set", nil, "Template value in the form key=value, can be repeated")
+	soakCmd.Flags().DurationVar(&SoakDuration, "duration", 12*time.Hour, "Length of the soak")
+	soakCmd.Flags().DurationVar(&SoakWarmup, "warmup", 10*time.Minute, "How long the pipeline runs before sampling starts")
+	soakCmd.Flags().DurationVar(&SoakInterval, "interval", time.Minute, "Time between samples")
+	soakCmd.Flags().Float64Var(&SoakMemoryTolerance, "memory-tolerance", 0.2, "Fraction a pod's memory may grow over the soak before it is flagged as a leak")
+	soakCmd.Flags().Float64Var(&SoakMinR2, "min-r2", 0.8, "How linear memory growth must be, as R², to count as a leak rather than noise")
+	soakCmd.Flags().Float64Var(&SoakThroughputTolerance, "throughput-tolerance", 0.1, "Fraction a vertex's throughput may drop over the soak before it is flagged")
+	soakCmd.Flags().StringVarP(&SoakOutputDir, "output-dir", "o", util.RunRecordDir, "Directory the soak summary and run record are written to")
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/ayildirim21/numaflow-perfman/benchmark"
+	"github.com/ayildirim21/numaflow-perfman/pipeline"
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+var StartupFile string
+var StartupScenario string
+var StartupValues []string
+var StartupRepeats int
+var StartupTimeout time.Duration
+var StartupCooldown time.Duration
+var StartupOutputDir string
+
+// startupCmd represents the startup command
+var startupCmd = &cobra.Command{
+	Use:   "startup",
+	Short: "Measure pipeline startup and teardown latency",
+	Long: "The startup command creates a pipeline, times each stage from its creation until the first message is written " +
+		"at its sinks, then deletes it and times the teardown. With --repeats the pipeline is created and deleted several " +
+		"times and the distribution of every stage is reported",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if StartupRepeats < 1 {
+			return errors.New("--repeats must be at least 1")
+		}
+
+		values, err := pipeline.ParseValues(StartupValues)
+		if err != nil {
+			return err
+		}
+
+		name, manifest, err := renderPipeline(StartupFile, StartupScenario, values)
+		if err != nil {
+			return err
+		}
+
+		obj, err := preparePipeline(name, manifest)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		runner := benchmark.Runner{
+			DynamicClient: dynamicClient,
+			KubeClient:    kubeClient,
+			Namespace:     util.PerfmanNamespace,
+			Log:           log,
+		}
+		prom, err := newPrometheusClient()
+		if err != nil {
+			return err
+		}
+
+		var timings []*benchmark.StartupTiming
+		var runErr error
+		for i := 0; i < StartupRepeats; i++ {
+			if i > 0 && StartupCooldown > 0 {
+				log.Info("Cooling down", zap.Duration("cooldown", StartupCooldown))
+				select {
+				case <-ctx.Done():
+					runErr = ctx.Err()
+				case <-time.After(StartupCooldown):
+				}
+				if runErr != nil {
+					break
+				}
+			}
+
+			log.Info("Measuring startup", zap.String("pipeline", name), zap.Int("repeat", i+1), zap.Int("of", StartupRepeats))
+			timing, err := runner.MeasureStartup(ctx, obj.DeepCopy(), prom, StartupTimeout)
+			if timing != nil {
+				timings = append(timings, timing)
+			}
+			if err != nil {
+				runErr = err
+				break
+			}
+		}
+
+		if len(timings) > 0 {
+			if err := benchmark.WriteStartupTable(os.Stdout, timings); err != nil {
+				return err
+			}
+
+			path, err := benchmark.SaveStartupTimings(StartupOutputDir, timings)
+			if err != nil {
+				return err
+			}
+			log.Info("Saved startup timings", zap.String("path", path))
+		}
+
+		if runErr != nil {
+			if errors.Is(runErr, context.Canceled) {
+				return errors.New("startup measurement interrupted")
+			}
+			return fmt.Errorf("startup measurement failed: %w", runErr)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(startupCmd)
+
+	startupCmd.Flags().StringVarP(&StartupFile, "file", "f", util.DefaultPipelineFile, "Pipeline manifest to measure")
+	startupCmd.Flags().StringVar(&StartupScenario, "scenario", "", "Built-in pipeline scenario to measure instead of --file")
+	startupCmd.Flags().StringArrayVar(&StartupValues, "set", nil, "Template value in the form key=value, can be repeated")
+	startupCmd.Flags().IntVar(&StartupRepeats, "repeats", 1, "How many times to create and delete the pipeline")
+	startupCmd.Flags().DurationVar(&StartupTimeout, "timeout", 5*time.Minute, "How long to wait for the first message at the sinks once the pipeline is running")
+	startupCmd.Flags().DurationVar(&StartupCooldown, "cooldown", 30*time.Second, "Pause between repeats")
+	startupCmd.Flags().StringVarP(&StartupOutputDir, "output-dir", "o", util.RunRecordDir, "Directory startup timings are written to")
+}
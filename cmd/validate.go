@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ayildirim21/numaflow-perfman/validate"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate FILE...",
+	Short: "Validate manifests against the installed CRD schemas",
+	Long: "The validate command checks manifests such as pipelines and ISB services against the openAPI schema of the " +
+		"CRDs installed on the cluster, reporting structural errors and unknown fields with file and line context. " +
+		"Pipelines whose installed CRD does not describe the spec are checked against a schema built into perfman",
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failed := 0
+		for _, filename := range args {
+			if err := validateManifest(filename); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d manifests failed validation", failed, len(args))
+		}
+
+		return nil
+	},
+}
+
+// validateManifest checks a manifest file against the cluster CRD schemas, printing every issue found.
+// It returns an error if the manifest cannot be validated or contains schema errors.
+func validateManifest(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("unable to validate %s: %w", filename, err)
+	}
+
+	return validateManifestData(filename, data)
+}
+
+// validateManifestData is validateManifest for a manifest already in memory, such as a rendered template
+func validateManifestData(filename string, data []byte) error {
+	issues, err := validate.ValidateManifest(filename, data, schemaFetcher.SchemaFor)
+	if err != nil {
+		return fmt.Errorf("unable to validate %s: %w", filename, err)
+	}
+
+	for _, issue := range issues {
+		fmt.Fprintln(os.Stderr, issue)
+	}
+
+	if validate.HasErrors(issues) {
+		return fmt.Errorf("%s failed schema validation", filename)
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
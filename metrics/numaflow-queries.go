@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Summary holds the headline metrics of a pipeline over a measurement window.
+// Metrics that had no data in the window are NaN.
+type Summary struct {
+	// Throughput is the rate, in messages per second, at which the sinks read messages
+	Throughput float64
+	// LatencyP50, LatencyP95 and LatencyP99 are forwarder batch processing times in milliseconds
+	LatencyP50 float64
+	LatencyP95 float64
+	LatencyP99 float64
+	// CPUCores is the average CPU usage of all pipeline pods
+	CPUCores float64
+	// MemoryBytes is the peak working set of all pipeline pods
+	MemoryBytes float64
+}
+
+// PipelineSelector returns the label matchers selecting the numaflow metrics of a pipeline,
+// optionally narrowed to some of its vertices
+func PipelineSelector(namespace string, pipeline string, vertices ...string) string {
+	matchers := fmt.Sprintf(`namespace="%s", pipeline="%s"`, namespace, pipeline)
+	if len(vertices) > 0 {
+		matchers += fmt.Sprintf(`, vertex=~"%s"`, strings.Join(vertices, "|"))
+	}
+
+	return "{" + matchers + "}"
+}
+
+// PodSelector returns the label matchers selecting the container metrics of all pods of a pipeline
+func PodSelector(namespace string, pipeline string) string {
+	return fmt.Sprintf(`{namespace="%s", pod=~"%s-.*", container!=""}`, namespace, pipeline)
+}
+
+// ReadRateQuery is the rate, in messages per second, at which the given vertices read messages
+func ReadRateQuery(namespace string, pipeline string, vertices []string, window time.Duration) string {
+	return fmt.Sprintf("sum(rate(forwarder_read_total%s[%s]))", PipelineSelector(namespace, pipeline, vertices...), promDuration(window))
+}
+
+// ReadRateByPipelineQuery is the rate, in messages per second, at which the given vertices of every pipeline
+// whose name matches the regular expression read messages, by pipeline
+func ReadRateByPipelineQuery(namespace string, pipelineRegex string, vertices []string, window time.Duration) string {
+	return fmt.Sprintf(`sum by (pipeline) (rate(forwarder_read_total{namespace="%s", pipeline=~"%s", vertex=~"%s"}[%s]))`,
+		namespace, pipelineRegex, strings.Join(vertices, "|"), promDuration(window))
+}
+
+// WriteRateQuery is the rate, in messages per second, at which the given vertices write messages
+func WriteRateQuery(namespace string, pipeline string, vertices []string, window time.Duration) string {
+	return fmt.Sprintf("sum(rate(forwarder_write_total%s[%s]))", PipelineSelector(namespace, pipeline, vertices...), promDuration(window))
+}
+
+// AckRateQuery is the rate, in messages per second, at which the given vertices acknowledge messages
+func AckRateQuery(namespace string, pipeline string, vertices []string, window time.Duration) string {
+	return fmt.Sprintf("sum(rate(forwarder_ack_total%s[%s]))", PipelineSelector(namespace, pipeline, vertices...), promDuration(window))
+}
+
+// PendingQuery is the number of messages pending in front of the given vertices
+func PendingQuery(namespace string, pipeline string, vertices []string) string {
+	return fmt.Sprintf(`sum(vertex_pending_messages{namespace="%s", pipeline="%s", vertex=~"%s", period="default"})`,
+		namespace, pipeline, strings.Join(vertices, "|"))
+}
+
+// LatencyQuery is the given quantile of the forwarder batch processing time of a pipeline, in milliseconds
+func LatencyQuery(namespace string, pipeline string, quantile float64, window time.Duration) string {
+	return fmt.Sprintf("histogram_quantile(%g, sum by (le) (rate(forwarder_forward_chunk_processing_time_bucket%s[%s]))) / 1000",
+		quantile, PipelineSelector(namespace, pipeline), promDuration(window))
+}
+
+// CPUQuery is the CPU usage of all pods of a pipeline, in cores
+func CPUQuery(namespace string, pipeline string, window time.Duration) string {
+	return fmt.Sprintf("sum(rate(container_cpu_usage_seconds_total%s[%s]))", PodSelector(namespace, pipeline), promDuration(window))
+}
+
+// MemoryQuery is the peak working set of all pods of a pipeline, in bytes
+func MemoryQuery(namespace string, pipeline string, window time.Duration) string {
+	return fmt.Sprintf("sum(max_over_time(container_memory_working_set_bytes%s[%s]))", PodSelector(namespace, pipeline), promDuration(window))
+}
+
+const reconcileBuckets = `controller_runtime_reconcile_time_seconds_bucket{controller="pipeline-controller"}`
+
+// ReconcileLatencyQuery is the given quantile of the time the numaflow controller takes to reconcile a pipeline,
+// in milliseconds. It only has data if Prometheus scrapes the controller.
+func ReconcileLatencyQuery(quantile float64, window time.Duration) string {
+	return fmt.Sprintf(`histogram_quantile(%g, sum by (le) (rate(%s[%s]))) * 1000`, quantile, reconcileBuckets, promDuration(window))
+}
+
+// ReconcileSeriesQuery counts the reconcile time series of the numaflow controller, it has no data unless
+// Prometheus scrapes the controller
+func ReconcileSeriesQuery() string {
+	return fmt.Sprintf("count(%s)", reconcileBuckets)
+}
+
+// Summarize computes the headline metrics of a pipeline over [start, end]
+func Summarize(ctx context.Context, c *PrometheusClient, namespace string, pipeline string, sinks []string, start time.Time, end time.Time) (*Summary, error) {
+	window := end.Sub(start)
+	s := &Summary{}
+	queries := []struct {
+		query  string
+		target *float64
+	}{
+		{ReadRateQuery(namespace, pipeline, sinks, window), &s.Throughput},
+		{LatencyQuery(namespace, pipeline, 0.5, window), &s.LatencyP50},
+		{LatencyQuery(namespace, pipeline, 0.95, window), &s.LatencyP95},
+		{LatencyQuery(namespace, pipeline, 0.99, window), &s.LatencyP99},
+		{CPUQuery(namespace, pipeline, window), &s.CPUCores},
+		{MemoryQuery(namespace, pipeline, window), &s.MemoryBytes},
+	}
+
+	for _, q := range queries {
+		v, err := c.ScalarOrNaN(ctx, q.query, end)
+		if err != nil {
+			return nil, err
+		}
+		*q.target = v
+	}
+
+	return s, nil
+}
+
+// promDuration formats d as a PromQL range duration, at least one second long
+func promDuration(d time.Duration) string {
+	seconds := int(d.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return fmt.Sprintf("%ds", seconds)
+}
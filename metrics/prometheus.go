@@ -0,0 +1,199 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrNoData is returned when a query matched no series
+var ErrNoData = errors.New("query returned no data")
+
+// PrometheusClient runs PromQL queries against the Prometheus HTTP API
+type PrometheusClient struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// NewPrometheusClient returns a client for the Prometheus server at baseURL
+func NewPrometheusClient(baseURL string) *PrometheusClient {
+	return &PrometheusClient{
+		URL:        baseURL,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Sample is a single value of an instant vector
+type Sample struct {
+	Labels map[string]string
+	Time   time.Time
+	Value  float64
+}
+
+// Point is a single value of a range vector
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series is a labelled range vector
+type Series struct {
+	Labels map[string]string
+	Points []Point
+}
+
+type apiResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// Query evaluates an instant query at the given time
+func (c *PrometheusClient) Query(ctx context.Context, query string, at time.Time) ([]Sample, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("time", formatTime(at))
+
+	var result []struct {
+		Metric map[string]string `json:"metric"`
+		Value  [2]interface{}    `json:"value"`
+	}
+	if err := c.get(ctx, "/api/v1/query", params, &result); err != nil {
+		return nil, err
+	}
+
+	samples := make([]Sample, 0, len(result))
+	for _, r := range result {
+		t, v, err := parseValue(r.Value)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, Sample{Labels: r.Metric, Time: t, Value: v})
+	}
+
+	return samples, nil
+}
+
+// QueryRange evaluates a query over [start, end] at the given resolution
+func (c *PrometheusClient) QueryRange(ctx context.Context, query string, start time.Time, end time.Time, step time.Duration) ([]Series, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", formatTime(start))
+	params.Set("end", formatTime(end))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	var result []struct {
+		Metric map[string]string `json:"metric"`
+		Values [][2]interface{}  `json:"values"`
+	}
+	if err := c.get(ctx, "/api/v1/query_range", params, &result); err != nil {
+		return nil, err
+	}
+
+	series := make([]Series, 0, len(result))
+	for _, r := range result {
+		s := Series{Labels: r.Metric, Points: make([]Point, 0, len(r.Values))}
+		for _, value := range r.Values {
+			t, v, err := parseValue(value)
+			if err != nil {
+				return nil, err
+			}
+			s.Points = append(s.Points, Point{Time: t, Value: v})
+		}
+		series = append(series, s)
+	}
+
+	return series, nil
+}
+
+// Scalar evaluates a query expected to return a single series and returns its value,
+// or ErrNoData if nothing matched
+func (c *PrometheusClient) Scalar(ctx context.Context, query string, at time.Time) (float64, error) {
+	samples, err := c.Query(ctx, query, at)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(samples) == 0 {
+		return 0, ErrNoData
+	}
+
+	return samples[0].Value, nil
+}
+
+// ScalarOrNaN is Scalar, returning NaN rather than an error when the query matched no data
+func (c *PrometheusClient) ScalarOrNaN(ctx context.Context, query string, at time.Time) (float64, error) {
+	v, err := c.Scalar(ctx, query, at)
+	if errors.Is(err, ErrNoData) {
+		return math.NaN(), nil
+	}
+
+	return v, err
+}
+
+func (c *PrometheusClient) get(ctx context.Context, path string, params url.Values, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build prometheus request: %w", err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query prometheus: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read prometheus response: %w", err)
+	}
+
+	var apiResp apiResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return fmt.Errorf("failed to parse prometheus response (status %d): %w", resp.StatusCode, err)
+	}
+
+	if apiResp.Status != "success" {
+		return fmt.Errorf("prometheus query failed: %s: %s", apiResp.ErrorType, apiResp.Error)
+	}
+
+	if err := json.Unmarshal(apiResp.Data.Result, result); err != nil {
+		return fmt.Errorf("unexpected %s result from prometheus: %w", apiResp.Data.ResultType, err)
+	}
+
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', 3, 64)
+}
+
+func parseValue(value [2]interface{}) (time.Time, float64, error) {
+	ts, ok := value[0].(float64)
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("unexpected sample timestamp %v", value[0])
+	}
+
+	s, ok := value[1].(string)
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("unexpected sample value %v", value[1])
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("unexpected sample value %q: %w", s, err)
+	}
+
+	return time.Unix(0, int64(ts*1e9)), v, nil
+}
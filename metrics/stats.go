@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+)
+
+// Fit is a least squares line through a series of points, with time measured in hours from the first point
+type Fit struct {
+	// Slope is the change per hour
+	Slope float64
+	// Intercept is the fitted value at the first point
+	Intercept float64
+	// R2 is the coefficient of determination, how well the line explains the points
+	R2 float64
+	// Hours is the time spanned by the points
+	Hours float64
+}
+
+// LinearFit fits a line through the points, which must be in time order.
+// Fewer than two points, or points all at the same time, give a zero fit.
+func LinearFit(points []Point) Fit {
+	if len(points) < 2 {
+		return Fit{}
+	}
+
+	t0 := points[0].Time
+	n := float64(len(points))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		x := p.Time.Sub(t0).Hours()
+		sumX += x
+		sumY += p.Value
+		sumXY += x * p.Value
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return Fit{}
+	}
+
+	fit := Fit{Hours: points[len(points)-1].Time.Sub(t0).Hours()}
+	fit.Slope = (n*sumXY - sumX*sumY) / denominator
+	fit.Intercept = (sumY - fit.Slope*sumX) / n
+
+	meanY := sumY / n
+	var ssTotal, ssResidual float64
+	for _, p := range points {
+		x := p.Time.Sub(t0).Hours()
+		residual := p.Value - (fit.Intercept + fit.Slope*x)
+		ssResidual += residual * residual
+		ssTotal += (p.Value - meanY) * (p.Value - meanY)
+	}
+	if ssTotal > 0 {
+		fit.R2 = 1 - ssResidual/ssTotal
+	}
+
+	return fit
+}
+
+// RelativeChange is the fitted change over the whole series relative to the fitted starting value
+func (f Fit) RelativeChange() float64 {
+	if f.Intercept == 0 {
+		return math.NaN()
+	}
+
+	return f.Slope * f.Hours / f.Intercept
+}
+
+// Percentile returns the p-th percentile, p in [0, 100], of values by linear interpolation between
+// the closest ranks, or NaN if there are no values
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
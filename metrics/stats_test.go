@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func series(values ...float64) []Point {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := make([]Point, len(values))
+	for i, v := range values {
+		points[i] = Point{Time: start.Add(time.Duration(i) * time.Hour), Value: v}
+	}
+
+	return points
+}
+
+func approxEqual(a float64, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestLinearFit(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		points []Point
+		want   Fit
+	}{
+		{name: "no points", points: nil, want: Fit{}},
+		{name: "single point", points: series(5), want: Fit{}},
+		{
+			name:   "points at the same time",
+			points: []Point{{Time: start, Value: 1}, {Time: start, Value: 2}},
+			want:   Fit{},
+		},
+		{name: "flat", points: series(4, 4, 4), want: Fit{Slope: 0, Intercept: 4, R2: 0, Hours: 2}},
+		{name: "perfect line", points: series(10, 12, 14, 16), want: Fit{Slope: 2, Intercept: 10, R2: 1, Hours: 3}},
+		{name: "falling line", points: series(9, 6, 3), want: Fit{Slope: -3, Intercept: 9, R2: 1, Hours: 2}},
+		{name: "noisy line", points: series(1, 3, 2, 4), want: Fit{Slope: 0.8, Intercept: 1.3, R2: 0.64, Hours: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := LinearFit(tt.points)
+			if !approxEqual(got.Slope, tt.want.Slope) || !approxEqual(got.Intercept, tt.want.Intercept) ||
+				!approxEqual(got.R2, tt.want.R2) || !approxEqual(got.Hours, tt.want.Hours) {
+				t.Errorf("LinearFit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelativeChange(t *testing.T) {
+	tests := []struct {
+		name string
+		fit  Fit
+		want float64
+	}{
+		{name: "growth", fit: Fit{Slope: 2, Intercept: 10, Hours: 3}, want: 0.6},
+		{name: "decline", fit: Fit{Slope: -3, Intercept: 9, Hours: 2}, want: -2.0 / 3},
+		{name: "flat", fit: Fit{Slope: 0, Intercept: 4, Hours: 2}, want: 0},
+		{name: "zero intercept", fit: Fit{Slope: 1, Intercept: 0, Hours: 2}, want: math.NaN()},
+		{name: "zero fit", fit: Fit{}, want: math.NaN()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fit.RelativeChange(); !approxEqual(got, tt.want) {
+				t.Errorf("RelativeChange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
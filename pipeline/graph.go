@@ -0,0 +1,140 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	VertexSource = "source"
+	VertexUDF    = "udf"
+	VertexReduce = "reduce"
+	VertexSink   = "sink"
+)
+
+// Vertex is a single vertex of a pipeline spec
+type Vertex struct {
+	Name string
+	Kind string
+	Spec map[string]interface{}
+}
+
+// Edge connects two vertices of a pipeline spec
+type Edge struct {
+	From string
+	To   string
+}
+
+// Graph is the topology described by a Pipeline's spec.vertices and spec.edges
+type Graph struct {
+	Name     string
+	Vertices []Vertex
+	Edges    []Edge
+	Limits   map[string]interface{}
+}
+
+// ParseGraph builds the topology graph of a Pipeline object
+func ParseGraph(obj *unstructured.Unstructured) (*Graph, error) {
+	if obj.GetKind() != "Pipeline" {
+		return nil, fmt.Errorf("expected a Pipeline, got %q", obj.GetKind())
+	}
+
+	g := &Graph{Name: obj.GetName()}
+
+	vertices, _, err := unstructured.NestedSlice(obj.Object, "spec", "vertices")
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec.vertices: %w", err)
+	}
+
+	for i, v := range vertices {
+		spec, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("spec.vertices[%d] is not an object", i)
+		}
+
+		name, _ := spec["name"].(string)
+		g.Vertices = append(g.Vertices, Vertex{Name: name, Kind: vertexKind(spec), Spec: spec})
+	}
+
+	edges, _, err := unstructured.NestedSlice(obj.Object, "spec", "edges")
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec.edges: %w", err)
+	}
+
+	for i, e := range edges {
+		spec, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("spec.edges[%d] is not an object", i)
+		}
+
+		from, _ := spec["from"].(string)
+		to, _ := spec["to"].(string)
+		g.Edges = append(g.Edges, Edge{From: from, To: to})
+	}
+
+	g.Limits, _, _ = unstructured.NestedMap(obj.Object, "spec", "limits")
+
+	return g, nil
+}
+
+// Vertex returns the vertex with the given name, or nil if there is none
+func (g *Graph) Vertex(name string) *Vertex {
+	for i := range g.Vertices {
+		if g.Vertices[i].Name == name {
+			return &g.Vertices[i]
+		}
+	}
+
+	return nil
+}
+
+// VerticesOfKind returns the names of all vertices of the given kind
+func (g *Graph) VerticesOfKind(kind string) []string {
+	var names []string
+	for _, v := range g.Vertices {
+		if v.Kind == kind {
+			names = append(names, v.Name)
+		}
+	}
+
+	return names
+}
+
+// Generators returns the names of all generator source vertices
+func (g *Graph) Generators() []string {
+	var names []string
+	for _, v := range g.Vertices {
+		if _, found, _ := unstructured.NestedMap(v.Spec, "source", "generator"); found {
+			names = append(names, v.Name)
+		}
+	}
+
+	return names
+}
+
+// adjacency maps each vertex name to the names of the vertices its edges lead to
+func (g *Graph) adjacency() map[string][]string {
+	adj := make(map[string][]string)
+	for _, e := range g.Edges {
+		adj[e.From] = append(adj[e.From], e.To)
+	}
+
+	return adj
+}
+
+func vertexKind(spec map[string]interface{}) string {
+	switch {
+	case spec["source"] != nil:
+		return VertexSource
+	case spec["sink"] != nil:
+		return VertexSink
+	case spec["udf"] != nil:
+		if _, found, _ := unstructured.NestedMap(spec, "udf", "groupBy"); found {
+			return VertexReduce
+		}
+		return VertexUDF
+	default:
+		return ""
+	}
+}
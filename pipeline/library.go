@@ -0,0 +1,184 @@
+package pipeline
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//go:embed library/*.yaml
+var libraryFS embed.FS
+
+// Knob is a tunable value of a scenario template
+type Knob struct {
+	Name        string
+	Default     string
+	Description string
+}
+
+// Scenario is a named, templated benchmark pipeline shipped with perfman
+type Scenario struct {
+	Name        string
+	Description string
+	Knobs       []Knob
+}
+
+var generatorKnobs = []Knob{
+	{Name: "rpu", Default: "5", Description: "messages the generator emits per duration"},
+	{Name: "duration", Default: "1s", Description: "generator emit interval"},
+	{Name: "msgSize", Default: "8", Description: "generated message size in bytes"},
+}
+
+var udfKnobs = []Knob{
+	{Name: "replicas", Default: "1", Description: "replicas pinned for every udf vertex"},
+	{Name: "partitions", Default: "1", Description: "partitions of the buffer in front of every udf vertex"},
+}
+
+// Library lists the built-in scenarios in the order they are presented to users
+var Library = []Scenario{
+	{
+		Name:        "map-cat",
+		Description: "generator -> builtin cat -> log sink",
+		Knobs:       append(append([]Knob{}, generatorKnobs...), udfKnobs...),
+	},
+	{
+		Name:        "reduce-sum",
+		Description: "keyed generator -> reduce-sum over a fixed window -> log sink",
+		Knobs: []Knob{
+			{Name: "rpu", Default: "5", Description: "messages the generator emits per duration"},
+			{Name: "duration", Default: "1s", Description: "generator emit interval"},
+			{Name: "keyCount", Default: "5", Description: "number of distinct keys generated"},
+			{Name: "window", Default: "60s", Description: "fixed window length"},
+			{Name: "partitions", Default: "1", Description: "partitions of the reduce vertex"},
+		},
+	},
+	{
+		Name:        "fan-out-3",
+		Description: "generator -> 3 parallel builtin cat vertices -> log sink",
+		Knobs:       append(append([]Knob{}, generatorKnobs...), udfKnobs...),
+	},
+	{
+		Name:        "join",
+		Description: "2 generators -> builtin cat -> log sink",
+		Knobs:       append(append([]Knob{}, generatorKnobs...), udfKnobs...),
+	},
+	{
+		Name:        "long-chain-10",
+		Description: "generator -> chain of builtin cat vertices -> log sink",
+		Knobs: append(append([]Knob{}, generatorKnobs...),
+			Knob{Name: "length", Default: "10", Description: "number of cat vertices in the chain"},
+			Knob{Name: "replicas", Default: "1", Description: "replicas pinned for every udf vertex"},
+		),
+	},
+	{
+		Name:        "backpressure",
+		Description: "fast generator -> cpu-throttled builtin cat -> log sink, with small buffers",
+		Knobs: []Knob{
+			{Name: "rpu", Default: "500", Description: "messages the generator emits per duration"},
+			{Name: "duration", Default: "1s", Description: "generator emit interval"},
+			{Name: "msgSize", Default: "8", Description: "generated message size in bytes"},
+			{Name: "bufferMaxLength", Default: "1000", Description: "maximum length of every buffer"},
+			{Name: "bufferUsageLimit", Default: "80", Description: "percentage of a buffer that may be used before writers back off"},
+			{Name: "readBatchSize", Default: "10", Description: "messages the slow vertex reads at once"},
+			{Name: "slowCpu", Default: "20m", Description: "cpu limit of the slow vertex"},
+		},
+	},
+}
+
+// LookupScenario returns the library scenario with the given name
+func LookupScenario(name string) (*Scenario, error) {
+	for i := range Library {
+		if Library[i].Name == name {
+			return &Library[i], nil
+		}
+	}
+
+	names := make([]string, 0, len(Library))
+	for _, s := range Library {
+		names = append(names, s.Name)
+	}
+
+	return nil, fmt.Errorf("unknown scenario %q, available scenarios: %s", name, strings.Join(names, ", "))
+}
+
+// Render produces the pipeline manifest of the scenario, with values overriding the knob defaults.
+// The pipeline is named after the scenario unless the name knob is set.
+func (s *Scenario) Render(values map[string]string) ([]byte, error) {
+	merged := map[string]string{"name": s.Name}
+	for _, k := range s.Knobs {
+		merged[k.Name] = k.Default
+	}
+
+	for k, v := range values {
+		if _, ok := merged[k]; !ok {
+			return nil, fmt.Errorf("scenario %s has no knob %q", s.Name, k)
+		}
+		merged[k] = v
+	}
+
+	tmpl, err := libraryFS.ReadFile("library/" + s.Name + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario template: %w", err)
+	}
+
+	return Render(s.Name, tmpl, merged)
+}
+
+// Render executes a pipeline manifest template with the given values.
+// Manifests without template actions are returned unchanged.
+func Render(name string, manifest []byte, values map[string]string) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(templateFuncs).Parse(string(manifest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, values); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// ParseValues turns key=value pairs, as given to --set flags, into template values
+func ParseValues(pairs []string) (map[string]string, error) {
+	values := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid value %q, expected key=value", pair)
+		}
+		values[k] = v
+	}
+
+	return values, nil
+}
+
+var templateFuncs = template.FuncMap{
+	"seq": func(n interface{}) ([]int, error) {
+		count, err := strconv.Atoi(fmt.Sprint(n))
+		if err != nil {
+			return nil, fmt.Errorf("seq expects a number, got %v", n)
+		}
+		if count < 0 {
+			return nil, fmt.Errorf("seq expects a non-negative number, got %d", count)
+		}
+		s := make([]int, count)
+		for i := range s {
+			s[i] = i + 1
+		}
+		return s, nil
+	},
+	"atoi": func(s string) (int, error) {
+		return strconv.Atoi(s)
+	},
+	"add": func(a int, b int) int {
+		return a + b
+	},
+	"fail": func(format string, args ...interface{}) (string, error) {
+		return "", fmt.Errorf(format, args...)
+	},
+}
@@ -0,0 +1,180 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+// GVR identifies numaflow Pipeline resources
+var GVR = schema.GroupVersionResource{
+	Group:    "numaflow.numaproj.io",
+	Version:  "v1alpha1",
+	Resource: "pipelines",
+}
+
+// VertexGVR identifies the numaflow Vertex resources the controller creates for each vertex of a pipeline
+var VertexGVR = schema.GroupVersionResource{
+	Group:    "numaflow.numaproj.io",
+	Version:  "v1alpha1",
+	Resource: "vertices",
+}
+
+// Pipeline phases reported in status.phase
+const (
+	PhaseRunning = "Running"
+	PhasePausing = "Pausing"
+	PhasePaused  = "Paused"
+	PhaseFailed  = "Failed"
+)
+
+const pollInterval = 2 * time.Second
+
+// Phase returns the current status.phase of a pipeline
+func Phase(ctx context.Context, dynamicClient *dynamic.DynamicClient, namespace string, name string) (string, error) {
+	obj, err := dynamicClient.Resource(GVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get pipeline %s: %w", name, err)
+	}
+
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	return phase, nil
+}
+
+// SetDesiredPhase patches spec.lifecycle.desiredPhase of a pipeline
+func SetDesiredPhase(ctx context.Context, dynamicClient *dynamic.DynamicClient, namespace string, name string, phase string) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"lifecycle":{"desiredPhase":%q}}}`, phase))
+	if _, err := dynamicClient.Resource(GVR).Namespace(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to set desired phase of pipeline %s to %s: %w", name, phase, err)
+	}
+
+	return nil
+}
+
+// WaitForPhase blocks until the pipeline reports the given phase, logging every intermediate phase it passes through
+func WaitForPhase(ctx context.Context, dynamicClient *dynamic.DynamicClient, namespace string, name string, phase string, log *zap.Logger) error {
+	lastPhase := ""
+	err := wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		current, err := Phase(ctx, dynamicClient, namespace, name)
+		if err != nil {
+			return false, err
+		}
+
+		if current != lastPhase {
+			if current == PhasePausing {
+				log.Info("Pipeline draining before pause", zap.String("pipeline", name))
+			} else {
+				log.Info("Pipeline phase changed", zap.String("pipeline", name), zap.String("phase", current))
+			}
+			lastPhase = current
+		}
+
+		if current == PhaseFailed && phase != PhaseFailed {
+			return false, fmt.Errorf("pipeline %s failed", name)
+		}
+
+		return current == phase, nil
+	})
+	if err != nil {
+		return fmt.Errorf("pipeline %s did not reach phase %s: %w", name, phase, err)
+	}
+
+	return nil
+}
+
+// Pause drains and pauses a pipeline, returning how long the transition took
+func Pause(ctx context.Context, dynamicClient *dynamic.DynamicClient, namespace string, name string, log *zap.Logger) (time.Duration, error) {
+	start := time.Now()
+	if err := SetDesiredPhase(ctx, dynamicClient, namespace, name, PhasePaused); err != nil {
+		return 0, err
+	}
+
+	if err := WaitForPhase(ctx, dynamicClient, namespace, name, PhasePaused, log); err != nil {
+		return 0, err
+	}
+
+	return time.Since(start), nil
+}
+
+// Resume resumes a paused pipeline, returning how long it took to be running again
+func Resume(ctx context.Context, dynamicClient *dynamic.DynamicClient, namespace string, name string, log *zap.Logger) (time.Duration, error) {
+	start := time.Now()
+	if err := SetDesiredPhase(ctx, dynamicClient, namespace, name, PhaseRunning); err != nil {
+		return 0, err
+	}
+
+	if err := WaitForPhase(ctx, dynamicClient, namespace, name, PhaseRunning, log); err != nil {
+		return 0, err
+	}
+
+	return time.Since(start), nil
+}
+
+// Restart pauses and then resumes a pipeline, returning how long the whole cycle took
+func Restart(ctx context.Context, dynamicClient *dynamic.DynamicClient, namespace string, name string, log *zap.Logger) (time.Duration, error) {
+	pauseTime, err := Pause(ctx, dynamicClient, namespace, name, log)
+	if err != nil {
+		return 0, err
+	}
+
+	resumeTime, err := Resume(ctx, dynamicClient, namespace, name, log)
+	if err != nil {
+		return 0, err
+	}
+
+	return pauseTime + resumeTime, nil
+}
+
+// Delete deletes a pipeline and waits until both the pipeline and its pods are gone, returning how long that took
+func Delete(ctx context.Context, dynamicClient *dynamic.DynamicClient, kubeClient *kubernetes.Clientset, namespace string, name string, log *zap.Logger) (time.Duration, error) {
+	start := time.Now()
+	resourceInterface := dynamicClient.Resource(GVR).Namespace(namespace)
+	if err := resourceInterface.Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return 0, fmt.Errorf("failed to delete pipeline %s: %w", name, err)
+	}
+
+	err := wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		_, err := resourceInterface.Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("pipeline %s was not removed: %w", name, err)
+	}
+
+	lastCount := -1
+	err = wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		pods, err := kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: util.PipelineNameLabel + "=" + name,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		if len(pods.Items) != lastCount && len(pods.Items) > 0 {
+			log.Info("Waiting for pipeline pods to terminate", zap.String("pipeline", name), zap.Int("remaining-pods", len(pods.Items)))
+			lastCount = len(pods.Items)
+		}
+
+		return len(pods.Items) == 0, nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("pods of pipeline %s were not removed: %w", name, err)
+	}
+
+	return time.Since(start), nil
+}
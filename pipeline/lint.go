@@ -0,0 +1,236 @@
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/ayildirim21/numaflow-perfman/validate"
+)
+
+// LintOptions tunes the checks performed by Lint
+type LintOptions struct {
+	// NumaflowVersion is the version of the numaflow controller the pipeline will run on, empty if unknown
+	NumaflowVersion string
+}
+
+// Issue is a single topology or configuration problem found in a pipeline
+type Issue struct {
+	Severity string
+	Vertex   string
+	Message  string
+}
+
+func (i Issue) String() string {
+	if i.Vertex == "" {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+
+	return fmt.Sprintf("%s: vertex %q: %s", i.Severity, i.Vertex, i.Message)
+}
+
+// HasErrors reports whether any of the issues is an error rather than a warning
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == validate.SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Lint checks the topology of a pipeline graph and warns about settings that make benchmarks unreliable
+func Lint(g *Graph, opts LintOptions) []Issue {
+	var issues []Issue
+	errorf := func(vertex string, format string, args ...interface{}) {
+		issues = append(issues, Issue{Severity: validate.SeverityError, Vertex: vertex, Message: fmt.Sprintf(format, args...)})
+	}
+	warnf := func(vertex string, format string, args ...interface{}) {
+		issues = append(issues, Issue{Severity: validate.SeverityWarning, Vertex: vertex, Message: fmt.Sprintf(format, args...)})
+	}
+
+	seen := make(map[string]bool)
+	for i, v := range g.Vertices {
+		switch {
+		case v.Name == "":
+			errorf("", "spec.vertices[%d] has no name", i)
+		case seen[v.Name]:
+			errorf(v.Name, "duplicate vertex name")
+		case v.Kind == "":
+			errorf(v.Name, "vertex is neither a source, a udf nor a sink")
+		}
+		seen[v.Name] = true
+	}
+
+	if len(g.VerticesOfKind(VertexSource)) == 0 {
+		errorf("", "pipeline has no source vertex")
+	}
+	if len(g.VerticesOfKind(VertexSink)) == 0 {
+		errorf("", "pipeline has no sink vertex")
+	}
+
+	outgoing := make(map[string]int)
+	for _, e := range g.Edges {
+		from, to := g.Vertex(e.From), g.Vertex(e.To)
+		if from == nil {
+			errorf("", "edge %s -> %s references missing vertex %q", e.From, e.To, e.From)
+		}
+		if to == nil {
+			errorf("", "edge %s -> %s references missing vertex %q", e.From, e.To, e.To)
+		}
+		if from != nil && from.Kind == VertexSink {
+			errorf(from.Name, "sink has an outgoing edge to %q", e.To)
+		}
+		if to != nil && to.Kind == VertexSource {
+			errorf(to.Name, "source has an incoming edge from %q", e.From)
+		}
+		outgoing[e.From]++
+	}
+
+	reachable := g.reachableFromSources()
+	for _, v := range g.Vertices {
+		if v.Name == "" {
+			continue
+		}
+		if !reachable[v.Name] {
+			errorf(v.Name, "vertex is not reachable from any source")
+		}
+		if v.Kind != VertexSink && outgoing[v.Name] == 0 {
+			warnf(v.Name, "vertex has no outgoing edges, its output is dropped")
+		}
+	}
+
+	if cycle := g.findCycle(); cycle != nil {
+		path := strings.Join(cycle, " -> ")
+		supported, err := SupportsCycles(opts.NumaflowVersion)
+		switch {
+		case err != nil:
+			warnf("", "pipeline contains cycle %s, unable to check numaflow support for cycles: %s", path, err)
+		case !supported:
+			errorf("", "pipeline contains cycle %s, which numaflow %s does not support", path, opts.NumaflowVersion)
+		}
+	}
+
+	if len(g.Limits) == 0 {
+		hasVertexLimits := false
+		for _, v := range g.Vertices {
+			if v.Spec["limits"] != nil {
+				hasVertexLimits = true
+				break
+			}
+		}
+		if !hasVertexLimits {
+			warnf("", "no limits set, numaflow defaults for batch and buffer sizes apply")
+		}
+	}
+
+	for _, v := range g.Vertices {
+		generator, found, _ := unstructured.NestedMap(v.Spec, "source", "generator")
+		if !found {
+			continue
+		}
+		if generator["duration"] == nil {
+			warnf(v.Name, "generator duration unset, the default of 1s applies")
+		}
+		if generator["rpu"] == nil {
+			warnf(v.Name, "generator rpu unset, the default of 5 messages per duration applies")
+		}
+	}
+
+	return issues
+}
+
+// SupportsCycles reports whether the given numaflow version allows cycles in a pipeline, which arrived in v1.1
+func SupportsCycles(version string) (bool, error) {
+	if version == "" {
+		return false, fmt.Errorf("numaflow version unknown")
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return false, fmt.Errorf("unrecognized numaflow version %q", version)
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false, fmt.Errorf("unrecognized numaflow version %q", version)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false, fmt.Errorf("unrecognized numaflow version %q", version)
+	}
+
+	return major > 1 || (major == 1 && minor >= 1), nil
+}
+
+func (g *Graph) reachableFromSources() map[string]bool {
+	adj := g.adjacency()
+	reachable := make(map[string]bool)
+	queue := g.VerticesOfKind(VertexSource)
+	for _, s := range queue {
+		reachable[s] = true
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range adj[current] {
+			if !reachable[next] {
+				reachable[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return reachable
+}
+
+// findCycle returns the vertices of the first cycle found, starting and ending with the same vertex, or nil
+func (g *Graph) findCycle() []string {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+
+	adj := g.adjacency()
+	state := make(map[string]int)
+	var stack []string
+	var cycle []string
+
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		state[name] = inProgress
+		stack = append(stack, name)
+		for _, next := range adj[name] {
+			switch state[next] {
+			case inProgress:
+				for i, s := range stack {
+					if s == next {
+						cycle = append(append([]string{}, stack[i:]...), next)
+						break
+					}
+				}
+				return true
+			case unvisited:
+				if visit(next) {
+					return true
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+		return false
+	}
+
+	for _, v := range g.Vertices {
+		if state[v.Name] == unvisited && visit(v.Name) {
+			return cycle
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package pipeline
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ayildirim21/numaflow-perfman/validate"
+)
+
+func graphOf(vertices []string, edges ...Edge) *Graph {
+	g := &Graph{Name: "test", Edges: edges}
+	for _, v := range vertices {
+		g.Vertices = append(g.Vertices, Vertex{Name: v})
+	}
+
+	return g
+}
+
+func TestFindCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph *Graph
+		want  []string
+	}{
+		{
+			name:  "chain",
+			graph: graphOf([]string{"in", "p1", "out"}, Edge{"in", "p1"}, Edge{"p1", "out"}),
+			want:  nil,
+		},
+		{
+			name:  "diamond",
+			graph: graphOf([]string{"in", "a", "b", "out"}, Edge{"in", "a"}, Edge{"in", "b"}, Edge{"a", "out"}, Edge{"b", "out"}),
+			want:  nil,
+		},
+		{
+			name:  "self loop",
+			graph: graphOf([]string{"in", "p1", "out"}, Edge{"in", "p1"}, Edge{"p1", "p1"}, Edge{"p1", "out"}),
+			want:  []string{"p1", "p1"},
+		},
+		{
+			name:  "loop back",
+			graph: graphOf([]string{"in", "p1", "p2", "out"}, Edge{"in", "p1"}, Edge{"p1", "p2"}, Edge{"p2", "p1"}, Edge{"p2", "out"}),
+			want:  []string{"p1", "p2", "p1"},
+		},
+		{
+			name:  "cycle not reachable from the first vertex",
+			graph: graphOf([]string{"in", "a", "b"}, Edge{"a", "b"}, Edge{"b", "a"}),
+			want:  []string{"a", "b", "a"},
+		},
+		{
+			name:  "no edges",
+			graph: graphOf([]string{"in", "out"}),
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.graph.findCycle(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findCycle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSupportsCycles(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+		wantErr bool
+	}{
+		{version: "v1.1.0", want: true},
+		{version: "v1.0.9", want: false},
+		{version: "v0.11.0", want: false},
+		{version: "v1.10.2", want: true},
+		{version: "v2.0.0", want: true},
+		{version: "1.3", want: true},
+		{version: "v1.2.1-rc1", want: true},
+		{version: "", wantErr: true},
+		{version: "latest", wantErr: true},
+		{version: "v1", wantErr: true},
+		{version: "v1.x.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := SupportsCycles(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SupportsCycles(%q) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SupportsCycles(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func generator(name string, settings map[string]interface{}) Vertex {
+	return Vertex{Name: name, Kind: VertexSource, Spec: map[string]interface{}{
+		"name":   name,
+		"source": map[string]interface{}{"generator": settings},
+	}}
+}
+
+func udf(name string) Vertex {
+	return Vertex{Name: name, Kind: VertexUDF, Spec: map[string]interface{}{"name": name, "udf": map[string]interface{}{}}}
+}
+
+func sink(name string) Vertex {
+	return Vertex{Name: name, Kind: VertexSink, Spec: map[string]interface{}{"name": name, "sink": map[string]interface{}{}}}
+}
+
+// lintGraph builds a graph with pipeline limits set, so only the issues a case is about are reported
+func lintGraph(vertices []Vertex, edges ...Edge) *Graph {
+	return &Graph{Name: "test", Vertices: vertices, Edges: edges, Limits: map[string]interface{}{"readBatchSize": int64(100)}}
+}
+
+func TestLint(t *testing.T) {
+	in := generator("in", map[string]interface{}{"rpu": int64(5), "duration": "1s"})
+	errorIssue := func(vertex string, message string) Issue {
+		return Issue{Severity: validate.SeverityError, Vertex: vertex, Message: message}
+	}
+	warningIssue := func(vertex string, message string) Issue {
+		return Issue{Severity: validate.SeverityWarning, Vertex: vertex, Message: message}
+	}
+	cyclic := func() *Graph {
+		return lintGraph([]Vertex{in, udf("p1"), udf("p2"), sink("out")},
+			Edge{"in", "p1"}, Edge{"p1", "p2"}, Edge{"p2", "p1"}, Edge{"p2", "out"})
+	}
+
+	tests := []struct {
+		name    string
+		graph   *Graph
+		version string
+		want    []Issue
+	}{
+		{
+			name:  "valid chain",
+			graph: lintGraph([]Vertex{in, udf("p1"), sink("out")}, Edge{"in", "p1"}, Edge{"p1", "out"}),
+			want:  nil,
+		},
+		{
+			name:  "empty name",
+			graph: lintGraph([]Vertex{in, {Kind: VertexUDF}, sink("out")}, Edge{"in", "out"}),
+			want:  []Issue{errorIssue("", "spec.vertices[1] has no name")},
+		},
+		{
+			name:  "duplicate name",
+			graph: lintGraph([]Vertex{in, udf("p1"), udf("p1"), sink("out")}, Edge{"in", "p1"}, Edge{"p1", "out"}),
+			want:  []Issue{errorIssue("p1", "duplicate vertex name")},
+		},
+		{
+			name:  "vertex without a kind",
+			graph: lintGraph([]Vertex{in, {Name: "x"}, sink("out")}, Edge{"in", "x"}, Edge{"x", "out"}),
+			want:  []Issue{errorIssue("x", "vertex is neither a source, a udf nor a sink")},
+		},
+		{
+			name:  "no source",
+			graph: lintGraph([]Vertex{udf("p1"), sink("out")}, Edge{"p1", "out"}),
+			want: []Issue{
+				errorIssue("", "pipeline has no source vertex"),
+				errorIssue("p1", "vertex is not reachable from any source"),
+				errorIssue("out", "vertex is not reachable from any source"),
+			},
+		},
+		{
+			name:  "no sink",
+			graph: lintGraph([]Vertex{in, udf("p1")}, Edge{"in", "p1"}),
+			want: []Issue{
+				errorIssue("", "pipeline has no sink vertex"),
+				warningIssue("p1", "vertex has no outgoing edges, its output is dropped"),
+			},
+		},
+		{
+			name:  "edge to a missing vertex",
+			graph: lintGraph([]Vertex{in, udf("p1"), sink("out")}, Edge{"in", "p1"}, Edge{"p1", "out"}, Edge{"p1", "ghost"}),
+			want:  []Issue{errorIssue("", `edge p1 -> ghost references missing vertex "ghost"`)},
+		},
+		{
+			name:  "edge from a missing vertex",
+			graph: lintGraph([]Vertex{in, sink("out")}, Edge{"in", "out"}, Edge{"ghost", "out"}),
+			want:  []Issue{errorIssue("", `edge ghost -> out references missing vertex "ghost"`)},
+		},
+		{
+			name:  "sink with an outgoing edge",
+			graph: lintGraph([]Vertex{in, sink("out"), sink("out2")}, Edge{"in", "out"}, Edge{"out", "out2"}),
+			want:  []Issue{errorIssue("out", `sink has an outgoing edge to "out2"`)},
+		},
+		{
+			name: "source with an incoming edge",
+			graph: lintGraph([]Vertex{in, generator("in2", map[string]interface{}{"rpu": int64(5), "duration": "1s"}), sink("out")},
+				Edge{"in", "in2"}, Edge{"in2", "out"}),
+			want: []Issue{errorIssue("in2", `source has an incoming edge from "in"`)},
+		},
+		{
+			name:  "unreachable vertex",
+			graph: lintGraph([]Vertex{in, udf("p1"), sink("out")}, Edge{"in", "out"}, Edge{"p1", "out"}),
+			want:  []Issue{errorIssue("p1", "vertex is not reachable from any source")},
+		},
+		{
+			name:  "dangling udf",
+			graph: lintGraph([]Vertex{in, udf("p1"), sink("out")}, Edge{"in", "p1"}, Edge{"in", "out"}),
+			want:  []Issue{warningIssue("p1", "vertex has no outgoing edges, its output is dropped")},
+		},
+		{
+			name:    "cycle on a version supporting cycles",
+			graph:   cyclic(),
+			version: "v1.1.0",
+			want:    nil,
+		},
+		{
+			name:    "cycle on an old version",
+			graph:   cyclic(),
+			version: "v1.0.0",
+			want:    []Issue{errorIssue("", "pipeline contains cycle p1 -> p2 -> p1, which numaflow v1.0.0 does not support")},
+		},
+		{
+			name:  "cycle on an unknown version",
+			graph: cyclic(),
+			want: []Issue{warningIssue("", "pipeline contains cycle p1 -> p2 -> p1, unable to check numaflow support for cycles: "+
+				"numaflow version unknown")},
+		},
+		{
+			name:  "no limits",
+			graph: &Graph{Name: "test", Vertices: []Vertex{in, sink("out")}, Edges: []Edge{{"in", "out"}}},
+			want:  []Issue{warningIssue("", "no limits set, numaflow defaults for batch and buffer sizes apply")},
+		},
+		{
+			name: "vertex limits only",
+			graph: &Graph{Name: "test", Edges: []Edge{{"in", "out"}}, Vertices: []Vertex{in, {Name: "out", Kind: VertexSink,
+				Spec: map[string]interface{}{"sink": map[string]interface{}{}, "limits": map[string]interface{}{"readBatchSize": int64(10)}}}}},
+			want: nil,
+		},
+		{
+			name:  "generator defaults",
+			graph: lintGraph([]Vertex{generator("in", map[string]interface{}{}), sink("out")}, Edge{"in", "out"}),
+			want: []Issue{
+				warningIssue("in", "generator duration unset, the default of 1s applies"),
+				warningIssue("in", "generator rpu unset, the default of 5 messages per duration applies"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lint(tt.graph, LintOptions{NumaflowVersion: tt.version}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+// VertexMutation edits the spec of a single vertex in place
+type VertexMutation func(vertex map[string]interface{}) error
+
+// UpdateVertex applies mutate to one vertex of a live pipeline, retrying on update conflicts
+func UpdateVertex(ctx context.Context, dynamicClient *dynamic.DynamicClient, namespace string, name string, vertexName string, mutate VertexMutation) error {
+	resourceInterface := dynamicClient.Resource(GVR).Namespace(namespace)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := resourceInterface.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get pipeline %s: %w", name, err)
+		}
+
+		vertices, _, err := unstructured.NestedSlice(obj.Object, "spec", "vertices")
+		if err != nil {
+			return fmt.Errorf("invalid spec.vertices of pipeline %s: %w", name, err)
+		}
+
+		found := false
+		for _, v := range vertices {
+			vertex, ok := v.(map[string]interface{})
+			if !ok || vertex["name"] != vertexName {
+				continue
+			}
+			if err := mutate(vertex); err != nil {
+				return err
+			}
+			found = true
+		}
+		if !found {
+			return fmt.Errorf("pipeline %s has no vertex %s", name, vertexName)
+		}
+
+		if err := unstructured.SetNestedSlice(obj.Object, vertices, "spec", "vertices"); err != nil {
+			return err
+		}
+
+		_, err = resourceInterface.Update(ctx, obj, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// SetGeneratorRPU is a VertexMutation setting the rate of a generator source
+func SetGeneratorRPU(rpu int64) VertexMutation {
+	return func(vertex map[string]interface{}) error {
+		if _, found, _ := unstructured.NestedMap(vertex, "source", "generator"); !found {
+			return fmt.Errorf("vertex %v is not a generator source", vertex["name"])
+		}
+
+		return unstructured.SetNestedField(vertex, rpu, "source", "generator", "rpu")
+	}
+}
+
+// SetReplicas is a VertexMutation pinning a vertex to a fixed number of replicas
+func SetReplicas(replicas int64) VertexMutation {
+	return func(vertex map[string]interface{}) error {
+		if err := unstructured.SetNestedField(vertex, replicas, "scale", "min"); err != nil {
+			return err
+		}
+
+		return unstructured.SetNestedField(vertex, replicas, "scale", "max")
+	}
+}
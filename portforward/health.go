@@ -0,0 +1,138 @@
+package portforward
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Defaults of the health checks of a forward
+const (
+	defaultHealthInterval = 30 * time.Second
+	defaultHealthTimeout  = 2 * time.Minute
+	healthProbeTimeout    = 5 * time.Second
+	healthRetryInterval   = time.Second
+)
+
+// Health paths of the services perfman forwards
+const (
+	PrometheusHealthPath = "/-/ready"
+	GrafanaHealthPath    = "/api/health"
+	NumaflowHealthPath   = "/livez"
+)
+
+// errStopped is returned by a health check interrupted by the forward being stopped
+var errStopped = errors.New("port forward stopped")
+
+// probeClient skips certificate verification, numaflow serves self-signed certificates and probes only ever go to localhost
+var probeClient = &http.Client{
+	Timeout:   healthProbeTimeout,
+	Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+}
+
+// Probe requests url and returns an error unless the service answers with a 2xx status
+func Probe(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := probeClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+
+	return nil
+}
+
+// HealthURL returns the url the forward's service is probed on, empty if it is not health checked
+func (f *Forward) HealthURL() string {
+	if f.HealthPath == "" {
+		return ""
+	}
+
+	return f.URL() + f.HealthPath
+}
+
+// waitHealthy probes the forward until its service responds, giving up after the health timeout or once stopCh is closed
+func (f *Forward) waitHealthy(stopCh <-chan struct{}) error {
+	timeout := f.HealthTimeout
+	if timeout == 0 {
+		timeout = defaultHealthTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		err := Probe(context.Background(), f.HealthURL())
+		if err == nil {
+			f.setHealthy(true)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			f.setHealthy(false)
+			return fmt.Errorf("%s did not become healthy within %s: %w", f.Name, timeout, err)
+		}
+
+		select {
+		case <-stopCh:
+			return errStopped
+		case <-time.After(healthRetryInterval):
+		}
+	}
+}
+
+// monitorHealth probes the forward every health interval until stopCh is closed, logging every change of its health
+func (f *Forward) monitorHealth(stopCh <-chan struct{}, log *zap.Logger) {
+	interval := f.HealthInterval
+	if interval == 0 {
+		interval = defaultHealthInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		err := Probe(context.Background(), f.HealthURL())
+		if healthy := err == nil; healthy != f.Healthy() {
+			f.setHealthy(healthy)
+			if healthy {
+				log.Info("Forwarded service is healthy again", zap.String("service", f.Name))
+			} else {
+				log.Warn("Forwarded service is unhealthy", zap.String("service", f.Name), zap.Error(err))
+			}
+		}
+	}
+}
+
+// Healthy reports whether the last probe of the forward succeeded, a forward without health checks is always healthy
+func (f *Forward) Healthy() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.HealthPath == "" || f.healthy
+}
+
+func (f *Forward) setHealthy(healthy bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.healthy = healthy
+}
@@ -1,20 +1,14 @@
 package portforward
 
 import (
-	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
-	"os"
-	"os/signal"
 	"strings"
-	"sync"
-	"syscall"
 
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericiooptions"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
@@ -50,7 +44,7 @@ func (req *APodRequest) PortForwardAPod() error {
 	}
 
 	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, &url.URL{Scheme: scheme, Path: path, Host: host})
-	fw, err := portforward.New(dialer, []string{fmt.Sprintf("%d:%d", req.LocalPort, req.PodPort)}, req.StopCh, req.ReadyCh, os.Stdout, os.Stderr)
+	fw, err := portforward.New(dialer, []string{fmt.Sprintf("%d:%d", req.LocalPort, req.PodPort)}, req.StopCh, req.ReadyCh, req.Streams.Out, req.Streams.ErrOut)
 	if err != nil {
 		return err
 	}
@@ -58,29 +52,24 @@ func (req *APodRequest) PortForwardAPod() error {
 	return fw.ForwardPorts()
 }
 
-func WaitForTermination(stopCh chan struct{}, wg *sync.WaitGroup) {
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-sigs
-		fmt.Println("Terminating connection...")
-		close(stopCh)
-		wg.Done()
-	}()
-}
-
-func GetPodFromService(kubeClient *kubernetes.Clientset, namespace string, serviceName string) (string, error) {
-	pods, err := kubeClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{
-		LabelSelector: "app.kubernetes.io/instance=" + serviceName,
-	})
+// FreePort returns a local port that is free at the time of the call
+func FreePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch pods: %w", err)
+		return 0, fmt.Errorf("unable to find a free local port: %w", err)
 	}
+	defer listener.Close()
 
-	if len(pods.Items) == 0 {
-		return "", fmt.Errorf("no matching pods found for %s", serviceName)
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// PortAvailable reports whether the local port can be listened on
+func PortAvailable(port int) bool {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return false
 	}
+	listener.Close()
 
-	firstPod := pods.Items[0].Name
-	return firstPod, nil
+	return true
 }
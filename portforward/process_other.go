@@ -0,0 +1,18 @@
+//go:build !unix
+
+package portforward
+
+import (
+	"os"
+	"syscall"
+)
+
+// DetachedProcAttr returns no attributes, processes are not tied to the terminal that started them
+func DetachedProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+func processAlive(pid int) bool {
+	_, err := os.FindProcess(pid)
+	return err == nil
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package portforward
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// DetachedProcAttr returns the attributes that start a process in its own session, so it outlives the terminal it was started from
+func DetachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	// Signal 0 only checks that the process exists, EPERM means it exists but belongs to someone else
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,170 @@
+package portforward
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Endpoint is a pod and the port of it that a service port routes to
+type Endpoint struct {
+	Pod  string
+	Port int
+}
+
+// ResolveService returns a ready pod behind the service and the container port the service port maps to.
+// The EndpointSlices of the service are preferred, they already hold ready pods and resolved target ports;
+// pods matching the service's selector are the fallback. servicePort may be zero if the service has a single port.
+func ResolveService(ctx context.Context, kubeClient kubernetes.Interface, namespace string, serviceName string, servicePort int) (Endpoint, error) {
+	svc, err := kubeClient.CoreV1().Services(namespace).Get(ctx, serviceName, metav1.GetOptions{})
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("failed to get service %s: %w", serviceName, err)
+	}
+
+	port, err := servicePortOf(svc, servicePort)
+	if err != nil {
+		return Endpoint{}, err
+	}
+
+	endpoint, found, err := endpointFromSlices(ctx, kubeClient, svc, port)
+	if err != nil || found {
+		return endpoint, err
+	}
+
+	return endpointFromSelector(ctx, kubeClient, svc, port)
+}
+
+func servicePortOf(svc *v1.Service, servicePort int) (v1.ServicePort, error) {
+	if servicePort == 0 && len(svc.Spec.Ports) == 1 {
+		return svc.Spec.Ports[0], nil
+	}
+	for _, p := range svc.Spec.Ports {
+		if int(p.Port) == servicePort {
+			return p, nil
+		}
+	}
+
+	return v1.ServicePort{}, fmt.Errorf("service %s has no port %d", svc.Name, servicePort)
+}
+
+// endpointFromSlices picks the first ready pod endpoint of the service, reporting whether the service has any slices
+func endpointFromSlices(ctx context.Context, kubeClient kubernetes.Interface, svc *v1.Service, port v1.ServicePort) (Endpoint, bool, error) {
+	slices, err := kubeClient.DiscoveryV1().EndpointSlices(svc.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", discoveryv1.LabelServiceName, svc.Name),
+	})
+	if err != nil {
+		return Endpoint{}, false, fmt.Errorf("failed to fetch endpoint slices of service %s: %w", svc.Name, err)
+	}
+	if len(slices.Items) == 0 {
+		return Endpoint{}, false, nil
+	}
+
+	for _, slice := range slices.Items {
+		// Slices name their ports after the service port and hold the target port it resolved to
+		targetPort := 0
+		for _, p := range slice.Ports {
+			if p.Port != nil && (p.Name == nil && port.Name == "" || p.Name != nil && *p.Name == port.Name) {
+				targetPort = int(*p.Port)
+			}
+		}
+		if targetPort == 0 {
+			continue
+		}
+
+		for _, e := range slice.Endpoints {
+			// A nil ready condition means ready
+			ready := e.Conditions.Ready == nil || *e.Conditions.Ready
+			terminating := e.Conditions.Terminating != nil && *e.Conditions.Terminating
+			if ready && !terminating && e.TargetRef != nil && e.TargetRef.Kind == "Pod" {
+				return Endpoint{Pod: e.TargetRef.Name, Port: targetPort}, true, nil
+			}
+		}
+	}
+
+	return Endpoint{}, true, fmt.Errorf("no ready endpoints found for service %s port %d", svc.Name, port.Port)
+}
+
+// endpointFromSelector picks the first ready pod matching the service's selector and resolves the target port on it
+func endpointFromSelector(ctx context.Context, kubeClient kubernetes.Interface, svc *v1.Service, port v1.ServicePort) (Endpoint, error) {
+	if len(svc.Spec.Selector) == 0 {
+		return Endpoint{}, fmt.Errorf("service %s has neither endpoint slices nor a selector", svc.Name)
+	}
+
+	pods, err := kubeClient.CoreV1().Pods(svc.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+	})
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("failed to fetch pods: %w", err)
+	}
+
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || !podReady(&pod) {
+			continue
+		}
+		if targetPort, ok := targetPortOf(&pod, port); ok {
+			return Endpoint{Pod: pod.Name, Port: targetPort}, nil
+		}
+	}
+
+	return Endpoint{}, fmt.Errorf("no ready pods found for service %s port %d", svc.Name, port.Port)
+}
+
+// targetPortOf resolves the target port of the service port on the pod, a named target port is looked up in its containers
+func targetPortOf(pod *v1.Pod, port v1.ServicePort) (int, bool) {
+	switch {
+	case port.TargetPort.Type == intstr.String && port.TargetPort.StrVal != "":
+		for _, c := range pod.Spec.Containers {
+			for _, p := range c.Ports {
+				if p.Name == port.TargetPort.StrVal {
+					return int(p.ContainerPort), true
+				}
+			}
+		}
+		return 0, false
+	case port.TargetPort.Type == intstr.Int && port.TargetPort.IntVal != 0:
+		return int(port.TargetPort.IntVal), true
+	default:
+		// An unset target port defaults to the service port
+		return int(port.Port), true
+	}
+}
+
+func podReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady && condition.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseServiceArg parses an argument of the form svc/<name>:<port> into the service name and port
+func ParseServiceArg(arg string) (string, int, error) {
+	name, found := strings.CutPrefix(arg, "svc/")
+	if !found {
+		name, found = strings.CutPrefix(arg, "service/")
+	}
+	if !found {
+		return "", 0, fmt.Errorf("%q is not of the form svc/<name>:<port>", arg)
+	}
+
+	name, portStr, found := strings.Cut(name, ":")
+	if !found || name == "" {
+		return "", 0, fmt.Errorf("%q is not of the form svc/<name>:<port>", arg)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port %q in %q", portStr, arg)
+	}
+
+	return name, port, nil
+}
@@ -0,0 +1,195 @@
+package portforward
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseServiceArg(t *testing.T) {
+	tests := []struct {
+		arg      string
+		wantName string
+		wantPort int
+		wantErr  bool
+	}{
+		{arg: "svc/prometheus:9090", wantName: "prometheus", wantPort: 9090},
+		{arg: "service/grafana:80", wantName: "grafana", wantPort: 80},
+		{arg: "svc/grafana:65535", wantName: "grafana", wantPort: 65535},
+		{arg: "grafana:80", wantErr: true},
+		{arg: "pod/grafana:80", wantErr: true},
+		{arg: "svc/grafana", wantErr: true},
+		{arg: "svc/:80", wantErr: true},
+		{arg: "svc/grafana:http", wantErr: true},
+		{arg: "svc/grafana:0", wantErr: true},
+		{arg: "svc/grafana:65536", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			name, port, err := ParseServiceArg(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseServiceArg(%q) error = %v, wantErr %v", tt.arg, err, tt.wantErr)
+			}
+			if name != tt.wantName || port != tt.wantPort {
+				t.Errorf("ParseServiceArg(%q) = %q, %d, want %q, %d", tt.arg, name, port, tt.wantName, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestTargetPortOf(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{
+		{Name: "main", Ports: []v1.ContainerPort{{Name: "http", ContainerPort: 3000}}},
+		{Name: "sidecar", Ports: []v1.ContainerPort{{Name: "metrics", ContainerPort: 9100}}},
+	}}}
+
+	tests := []struct {
+		name   string
+		port   v1.ServicePort
+		want   int
+		wantOK bool
+	}{
+		{name: "numeric target port", port: v1.ServicePort{Port: 80, TargetPort: intstr.FromInt32(8080)}, want: 8080, wantOK: true},
+		{name: "named target port", port: v1.ServicePort{Port: 80, TargetPort: intstr.FromString("http")}, want: 3000, wantOK: true},
+		{name: "named port of a sidecar", port: v1.ServicePort{Port: 9100, TargetPort: intstr.FromString("metrics")}, want: 9100, wantOK: true},
+		{name: "unknown named target port", port: v1.ServicePort{Port: 80, TargetPort: intstr.FromString("grpc")}, want: 0, wantOK: false},
+		{name: "unset target port", port: v1.ServicePort{Port: 80}, want: 80, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := targetPortOf(pod, tt.port)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("targetPortOf() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func endpointSlice(name string, ports []discoveryv1.EndpointPort, endpoints ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "grafana"},
+		},
+		Ports:     ports,
+		Endpoints: endpoints,
+	}
+}
+
+func podEndpoint(pod string, ready bool, terminating bool) discoveryv1.Endpoint {
+	return discoveryv1.Endpoint{
+		Conditions: discoveryv1.EndpointConditions{Ready: ptr(ready), Terminating: ptr(terminating)},
+		TargetRef:  &v1.ObjectReference{Kind: "Pod", Name: pod},
+	}
+}
+
+func TestEndpointFromSlices(t *testing.T) {
+	svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: "default"}}
+	unnamed := v1.ServicePort{Port: 80}
+	named := v1.ServicePort{Name: "http", Port: 80}
+
+	tests := []struct {
+		name      string
+		slices    []*discoveryv1.EndpointSlice
+		port      v1.ServicePort
+		want      Endpoint
+		wantFound bool
+		wantErr   bool
+	}{
+		{
+			name:      "no slices",
+			port:      unnamed,
+			wantFound: false,
+		},
+		{
+			name: "unnamed port with a nil name",
+			slices: []*discoveryv1.EndpointSlice{endpointSlice("grafana-a",
+				[]discoveryv1.EndpointPort{{Port: ptr(int32(3000))}}, podEndpoint("grafana-0", true, false))},
+			port:      unnamed,
+			want:      Endpoint{Pod: "grafana-0", Port: 3000},
+			wantFound: true,
+		},
+		{
+			name: "unnamed port with an empty name",
+			slices: []*discoveryv1.EndpointSlice{endpointSlice("grafana-a",
+				[]discoveryv1.EndpointPort{{Name: ptr(""), Port: ptr(int32(3000))}}, podEndpoint("grafana-0", true, false))},
+			port:      unnamed,
+			want:      Endpoint{Pod: "grafana-0", Port: 3000},
+			wantFound: true,
+		},
+		{
+			name: "named port picked among several",
+			slices: []*discoveryv1.EndpointSlice{endpointSlice("grafana-a",
+				[]discoveryv1.EndpointPort{{Name: ptr("metrics"), Port: ptr(int32(9100))}, {Name: ptr("http"), Port: ptr(int32(3000))}},
+				podEndpoint("grafana-0", true, false))},
+			port:      named,
+			want:      Endpoint{Pod: "grafana-0", Port: 3000},
+			wantFound: true,
+		},
+		{
+			name: "named service port does not match an unnamed slice port",
+			slices: []*discoveryv1.EndpointSlice{endpointSlice("grafana-a",
+				[]discoveryv1.EndpointPort{{Port: ptr(int32(3000))}}, podEndpoint("grafana-0", true, false))},
+			port:      named,
+			wantFound: true,
+			wantErr:   true,
+		},
+		{
+			name: "skips not ready and terminating pods",
+			slices: []*discoveryv1.EndpointSlice{endpointSlice("grafana-a",
+				[]discoveryv1.EndpointPort{{Port: ptr(int32(3000))}},
+				podEndpoint("grafana-0", false, false), podEndpoint("grafana-1", true, true), podEndpoint("grafana-2", true, false))},
+			port:      unnamed,
+			want:      Endpoint{Pod: "grafana-2", Port: 3000},
+			wantFound: true,
+		},
+		{
+			name: "nil ready condition means ready",
+			slices: []*discoveryv1.EndpointSlice{endpointSlice("grafana-a",
+				[]discoveryv1.EndpointPort{{Port: ptr(int32(3000))}},
+				discoveryv1.Endpoint{TargetRef: &v1.ObjectReference{Kind: "Pod", Name: "grafana-0"}})},
+			port:      unnamed,
+			want:      Endpoint{Pod: "grafana-0", Port: 3000},
+			wantFound: true,
+		},
+		{
+			name: "no ready endpoints",
+			slices: []*discoveryv1.EndpointSlice{endpointSlice("grafana-a",
+				[]discoveryv1.EndpointPort{{Port: ptr(int32(3000))}}, podEndpoint("grafana-0", false, false))},
+			port:      unnamed,
+			wantFound: true,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset()
+			for _, slice := range tt.slices {
+				if _, err := kubeClient.DiscoveryV1().EndpointSlices(slice.Namespace).Create(context.Background(), slice, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, found, err := endpointFromSlices(context.Background(), kubeClient, svc, tt.port)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("endpointFromSlices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("endpointFromSlices() = %+v, %v, want %+v, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package portforward
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Bounds of the delay between attempts to reconnect a lost forward
+const (
+	initialReconnectBackoff = time.Second
+	maxReconnectBackoff     = 30 * time.Second
+)
+
+// Forward is one named port forward of a session
+type Forward struct {
+	Name string
+	// Service is the service the pod of the request was resolved from, if any
+	Service string
+	// Scheme is the scheme of the URL of the forward, http if empty
+	Scheme  string
+	Request *APodRequest
+	// Resolve, if not nil, returns the pod and port to reconnect to once the forward is lost, those of the request are reused otherwise
+	Resolve func() (Endpoint, error)
+	// HealthPath, if not empty, is probed once the tunnel is up and every HealthInterval after; the forward is
+	// only ready once it responds, within HealthTimeout
+	HealthPath     string
+	HealthInterval time.Duration
+	HealthTimeout  time.Duration
+
+	mu      sync.Mutex
+	healthy bool
+}
+
+// URL returns the local address the forward listens on
+func (f *Forward) URL() string {
+	scheme := f.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	return fmt.Sprintf("%s://localhost:%d", scheme, f.Request.LocalPort)
+}
+
+// Endpoint returns the pod and port the forward is currently connected to
+func (f *Forward) Endpoint() Endpoint {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return Endpoint{Pod: f.Request.Pod.Name, Port: f.Request.PodPort}
+}
+
+func (f *Forward) setEndpoint(endpoint Endpoint) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Request.Pod.Name, f.Request.PodPort = endpoint.Pod, endpoint.Port
+}
+
+// RunSession forwards every request concurrently until stopCh is closed. A forward whose connection is lost, e.g. because
+// its pod restarted, is re-resolved and reconnected with backoff. A forward that fails before it was ever ready stops
+// all of them. The stop and ready channels of the requests are replaced by ones owned by the session.
+// ready, if not nil, is called once every forward is ready, and again after every reconnect.
+func RunSession(forwards []*Forward, stopCh <-chan struct{}, log *zap.Logger, ready func()) error {
+	if len(forwards) == 0 {
+		return errors.New("no forwards to run")
+	}
+
+	sessionStopCh := make(chan struct{})
+	var stopOnce sync.Once
+	stopAll := func() { stopOnce.Do(func() { close(sessionStopCh) }) }
+
+	var readyMu sync.Mutex
+	pending := len(forwards)
+	onReady := func(reconnect bool) {
+		readyMu.Lock()
+		defer readyMu.Unlock()
+
+		if !reconnect {
+			pending--
+		}
+		if pending == 0 && ready != nil {
+			ready()
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(forwards))
+	for i, f := range forwards {
+		wg.Add(1)
+		go func(i int, f *Forward) {
+			defer wg.Done()
+			if err := f.supervise(sessionStopCh, log, onReady); err != nil {
+				errs[i] = fmt.Errorf("port forward to %s failed: %w", f.Name, err)
+				stopAll()
+			}
+		}(i, f)
+	}
+
+	select {
+	case <-stopCh:
+	case <-sessionStopCh:
+	}
+	stopAll()
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// supervise keeps the forward connected until stopCh is closed, calling onReady whenever it becomes ready
+func (f *Forward) supervise(stopCh chan struct{}, log *zap.Logger, onReady func(reconnect bool)) error {
+	connected := false
+	backoff := initialReconnectBackoff
+	for {
+		// Each attempt has its own stop channel, an attempt whose service never becomes healthy is stopped on its own
+		attemptStopCh := make(chan struct{})
+		var stopOnce sync.Once
+		stopAttempt := func() { stopOnce.Do(func() { close(attemptStopCh) }) }
+		go func() {
+			select {
+			case <-stopCh:
+				stopAttempt()
+			case <-attemptStopCh:
+			}
+		}()
+
+		readyCh := make(chan struct{})
+		f.Request.StopCh, f.Request.ReadyCh = attemptStopCh, readyCh
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- f.Request.PortForwardAPod() }()
+
+		var err error
+		select {
+		case <-readyCh:
+			if f.HealthPath != "" {
+				if err = f.waitHealthy(attemptStopCh); err != nil {
+					stopAttempt()
+					<-errCh
+					break
+				}
+				go f.monitorHealth(attemptStopCh, log)
+			}
+
+			if connected {
+				log.Info("Reconnected port forward", zap.String("service", f.Name), zap.String("pod", f.Endpoint().Pod))
+			}
+			onReady(connected)
+			connected, backoff = true, initialReconnectBackoff
+			err = <-errCh
+		case err = <-errCh:
+		}
+		stopAttempt()
+
+		select {
+		case <-stopCh:
+			return nil
+		default:
+		}
+		if !connected {
+			return err
+		}
+
+		for {
+			log.Warn("Port forward lost, reconnecting", zap.String("service", f.Name), zap.String("pod", f.Endpoint().Pod),
+				zap.Duration("backoff", backoff), zap.Error(err))
+
+			select {
+			case <-stopCh:
+				return nil
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxReconnectBackoff)
+
+			if f.Resolve == nil {
+				break
+			}
+			endpoint, resolveErr := f.Resolve()
+			if resolveErr == nil {
+				f.setEndpoint(endpoint)
+				break
+			}
+			err = resolveErr
+		}
+	}
+}
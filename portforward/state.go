@@ -0,0 +1,129 @@
+package portforward
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrNoState is returned when no state file exists, i.e. no port forward daemon was started
+var ErrNoState = errors.New("no port forward daemon state found")
+
+// ServiceState is one forward of a running daemon
+type ServiceState struct {
+	Name      string `json:"name"`
+	Service   string `json:"service"`
+	Pod       string `json:"pod"`
+	LocalPort int    `json:"localPort"`
+	PodPort   int    `json:"podPort"`
+	URL       string `json:"url"`
+	// HealthURL is where the service is probed, empty if it is not health checked
+	HealthURL string `json:"healthUrl,omitempty"`
+}
+
+// State describes a port forward daemon, it is written once all its forwards are ready
+type State struct {
+	PID       int            `json:"pid"`
+	StartedAt time.Time      `json:"startedAt"`
+	LogFile   string         `json:"logFile"`
+	Services  []ServiceState `json:"services"`
+}
+
+// DefaultStatePath returns the path of the state file in the user's home directory, with the daemon's log file next to it
+func DefaultStatePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to locate the home directory: %w", err)
+	}
+
+	return filepath.Join(home, ".perfman", "portforward.json"), nil
+}
+
+// LogPath returns the path of the daemon's log file next to the state file
+func LogPath(statePath string) string {
+	return filepath.Join(filepath.Dir(statePath), "portforward.log")
+}
+
+// NewState returns the state of the forwards of the calling process
+func NewState(forwards []*Forward, logFile string) *State {
+	state := &State{PID: os.Getpid(), StartedAt: time.Now(), LogFile: logFile}
+	for _, f := range forwards {
+		endpoint := f.Endpoint()
+		state.Services = append(state.Services, ServiceState{
+			Name:      f.Name,
+			Service:   f.Service,
+			Pod:       endpoint.Pod,
+			LocalPort: f.Request.LocalPort,
+			PodPort:   endpoint.Port,
+			URL:       f.URL(),
+			HealthURL: f.HealthURL(),
+		})
+	}
+
+	return state
+}
+
+// Service returns the forward with the given name, or nil
+func (s *State) Service(name string) *ServiceState {
+	for i := range s.Services {
+		if s.Services[i].Name == name {
+			return &s.Services[i]
+		}
+	}
+
+	return nil
+}
+
+// Running reports whether the daemon's process is still alive
+func (s *State) Running() bool {
+	return processAlive(s.PID)
+}
+
+// WriteState writes the state file, replacing it atomically so readers never see a partial file
+func WriteState(path string, state *State) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal port forward state: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write port forward state: %w", err)
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// ReadState reads the state file, returning ErrNoState if it does not exist
+func ReadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNoState
+		}
+		return nil, fmt.Errorf("failed to read port forward state: %w", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse port forward state %s: %w", path, err)
+	}
+
+	return &state, nil
+}
+
+// RemoveState removes the state file, a missing file is not an error
+func RemoveState(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove port forward state: %w", err)
+	}
+
+	return nil
+}
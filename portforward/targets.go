@@ -0,0 +1,215 @@
+package portforward
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+// Kinds of targets that can be forwarded
+const (
+	TargetService    = "svc"
+	TargetNumaflowUI = "numaflow-ui"
+	TargetDaemon     = "daemon"
+	TargetVertex     = "vertex"
+)
+
+// Target is something to forward to, named on the command line
+type Target struct {
+	Kind string
+	// Name is the service of a svc target and the pipeline of a daemon or vertex target
+	Name   string
+	Vertex string
+	// Port is the service port of a svc target
+	Port int
+}
+
+// ParseTargets parses a list of targets, each one of svc/<name>:<port>, numaflow-ui, daemon <pipeline> or vertex <pipeline>/<vertex>
+func ParseTargets(args []string) ([]Target, error) {
+	var targets []Target
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case TargetNumaflowUI:
+			targets = append(targets, Target{Kind: TargetNumaflowUI})
+		case TargetDaemon:
+			if i+1 == len(args) {
+				return nil, fmt.Errorf("daemon needs a pipeline, as in daemon <pipeline>")
+			}
+			i++
+			targets = append(targets, Target{Kind: TargetDaemon, Name: args[i]})
+		case TargetVertex:
+			if i+1 == len(args) {
+				return nil, fmt.Errorf("vertex needs a pipeline and vertex, as in vertex <pipeline>/<vertex>")
+			}
+			i++
+			pipelineName, vertex, found := strings.Cut(args[i], "/")
+			if !found || pipelineName == "" || vertex == "" {
+				return nil, fmt.Errorf("%q is not of the form <pipeline>/<vertex>", args[i])
+			}
+			targets = append(targets, Target{Kind: TargetVertex, Name: pipelineName, Vertex: vertex})
+		default:
+			name, port, err := ParseServiceArg(args[i])
+			if err != nil {
+				return nil, fmt.Errorf("unknown target %q, expected svc/<name>:<port>, numaflow-ui, daemon <pipeline> or vertex <pipeline>/<vertex>", args[i])
+			}
+			targets = append(targets, Target{Kind: TargetService, Name: name, Port: port})
+		}
+	}
+
+	return targets, nil
+}
+
+// String names the forward of the target
+func (t Target) String() string {
+	switch t.Kind {
+	case TargetNumaflowUI:
+		return TargetNumaflowUI
+	case TargetDaemon:
+		return fmt.Sprintf("daemon/%s", t.Name)
+	case TargetVertex:
+		return fmt.Sprintf("vertex/%s/%s", t.Name, t.Vertex)
+	default:
+		return t.Name
+	}
+}
+
+// Namespace returns the namespace of the target, pipelines live in namespace
+func (t Target) Namespace(namespace string) string {
+	if t.Kind == TargetNumaflowUI {
+		return util.NumaflowNamespace
+	}
+
+	return namespace
+}
+
+// Scheme returns the scheme the target serves, numaflow serves everything over https
+func (t Target) Scheme() string {
+	if t.Kind == TargetService {
+		return "http"
+	}
+
+	return "https"
+}
+
+// DefaultLocalPort returns the port the target is forwarded to unless it is taken, the port it is served on
+func (t Target) DefaultLocalPort() int {
+	switch t.Kind {
+	case TargetNumaflowUI:
+		return util.NumaflowServerPort
+	case TargetDaemon:
+		return util.DaemonServicePort
+	case TargetVertex:
+		return util.VertexMetricsPort
+	default:
+		return t.Port
+	}
+}
+
+// HealthPath returns the path the target is probed on, services other than numaflow's are not probed
+func (t Target) HealthPath() string {
+	if t.Kind == TargetService {
+		return ""
+	}
+
+	return NumaflowHealthPath
+}
+
+// Resolve returns a ready pod of the target and the port on it to forward to. Daemons and vertices are found through
+// the labels numaflow sets on the resources of a pipeline.
+func (t Target) Resolve(ctx context.Context, kubeClient kubernetes.Interface, namespace string) (Endpoint, error) {
+	switch t.Kind {
+	case TargetNumaflowUI:
+		return ResolveService(ctx, kubeClient, util.NumaflowNamespace, util.NumaflowServerServiceName, util.NumaflowServerPort)
+	case TargetDaemon:
+		serviceName, err := daemonService(ctx, kubeClient, namespace, t.Name)
+		if err != nil {
+			return Endpoint{}, err
+		}
+		return ResolveService(ctx, kubeClient, namespace, serviceName, util.DaemonServicePort)
+	case TargetVertex:
+		return vertexPod(ctx, kubeClient, namespace, t.Name, t.Vertex)
+	default:
+		return ResolveService(ctx, kubeClient, namespace, t.Name, t.Port)
+	}
+}
+
+// Service returns the service the target is resolved through, vertices are resolved to a pod directly
+func (t Target) Service(ctx context.Context, kubeClient kubernetes.Interface, namespace string) (string, error) {
+	switch t.Kind {
+	case TargetNumaflowUI:
+		return util.NumaflowServerServiceName, nil
+	case TargetDaemon:
+		return daemonService(ctx, kubeClient, namespace, t.Name)
+	case TargetVertex:
+		return "", nil
+	default:
+		return t.Name, nil
+	}
+}
+
+func daemonService(ctx context.Context, kubeClient kubernetes.Interface, namespace string, pipelineName string) (string, error) {
+	services, err := kubeClient.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,%s=%s", util.PipelineNameLabel, pipelineName, util.ComponentLabel, util.DaemonComponent),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch the daemon service of pipeline %s: %w", pipelineName, err)
+	}
+	if len(services.Items) == 0 {
+		return "", fmt.Errorf("pipeline %s has no daemon service", pipelineName)
+	}
+
+	return services.Items[0].Name, nil
+}
+
+// vertexPod returns the ready pod of the vertex with the lowest replica index
+func vertexPod(ctx context.Context, kubeClient kubernetes.Interface, namespace string, pipelineName string, vertex string) (Endpoint, error) {
+	pods, err := kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,%s=%s,%s=%s", util.PipelineNameLabel, pipelineName, util.VertexNameLabel, vertex,
+			util.ComponentLabel, util.VertexComponent),
+	})
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("failed to fetch pods of vertex %s: %w", vertex, err)
+	}
+
+	prefix := fmt.Sprintf("%s-%s-", pipelineName, vertex)
+	sort.Slice(pods.Items, func(i, j int) bool {
+		a, aOK := replicaIndex(pods.Items[i].Name, prefix)
+		b, bOK := replicaIndex(pods.Items[j].Name, prefix)
+		if aOK && bOK && a != b {
+			return a < b
+		}
+		if aOK != bOK {
+			return aOK
+		}
+		return pods.Items[i].Name < pods.Items[j].Name
+	})
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp == nil && podReady(&pod) {
+			return Endpoint{Pod: pod.Name, Port: util.VertexMetricsPort}, nil
+		}
+	}
+
+	return Endpoint{}, fmt.Errorf("no ready pods found for vertex %s of pipeline %s", vertex, pipelineName)
+}
+
+// replicaIndex parses the replica of a vertex pod from its name, which numaflow forms as <pipeline>-<vertex>-<replica>-<suffix>
+func replicaIndex(podName string, prefix string) (int, bool) {
+	rest, found := strings.CutPrefix(podName, prefix)
+	if !found {
+		return 0, false
+	}
+	replica, _, _ := strings.Cut(rest, "-")
+	index, err := strconv.Atoi(replica)
+	if err != nil {
+		return 0, false
+	}
+
+	return index, true
+}
@@ -0,0 +1,143 @@
+package portforward
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+func TestParseTargets(t *testing.T) {
+	tests := []struct {
+		args    string
+		want    []Target
+		wantErr bool
+	}{
+		{args: "", want: nil},
+		{args: "numaflow-ui", want: []Target{{Kind: TargetNumaflowUI}}},
+		{args: "svc/grafana:80", want: []Target{{Kind: TargetService, Name: "grafana", Port: 80}}},
+		{args: "daemon simple-pipeline", want: []Target{{Kind: TargetDaemon, Name: "simple-pipeline"}}},
+		{args: "vertex simple-pipeline/p1", want: []Target{{Kind: TargetVertex, Name: "simple-pipeline", Vertex: "p1"}}},
+		{
+			args: "svc/prometheus:9090 daemon simple-pipeline numaflow-ui vertex simple-pipeline/output",
+			want: []Target{
+				{Kind: TargetService, Name: "prometheus", Port: 9090},
+				{Kind: TargetDaemon, Name: "simple-pipeline"},
+				{Kind: TargetNumaflowUI},
+				{Kind: TargetVertex, Name: "simple-pipeline", Vertex: "output"},
+			},
+		},
+		{args: "daemon", wantErr: true},
+		{args: "numaflow-ui daemon", wantErr: true},
+		{args: "vertex", wantErr: true},
+		{args: "svc/grafana:80 vertex", wantErr: true},
+		{args: "vertex simple-pipeline", wantErr: true},
+		{args: "vertex simple-pipeline/", wantErr: true},
+		{args: "vertex /p1", wantErr: true},
+		{args: "grafana", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			got, err := ParseTargets(strings.Fields(tt.args))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTargets(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTargets(%q) = %+v, want %+v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplicaIndex(t *testing.T) {
+	tests := []struct {
+		pod    string
+		want   int
+		wantOK bool
+	}{
+		{pod: "simple-pipeline-p1-0-7jzbn", want: 0, wantOK: true},
+		{pod: "simple-pipeline-p1-12-x2b4k", want: 12, wantOK: true},
+		{pod: "simple-pipeline-p1-3", want: 3, wantOK: true},
+		{pod: "simple-pipeline-p1-daemon-5d9c7-x2b4k", wantOK: false},
+		{pod: "other-pipeline-p1-0-7jzbn", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pod, func(t *testing.T) {
+			got, ok := replicaIndex(tt.pod, "simple-pipeline-p1-")
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("replicaIndex(%q) = %d, %v, want %d, %v", tt.pod, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestVertexPod(t *testing.T) {
+	vertexPodOf := func(name string, ready bool) *v1.Pod {
+		status := v1.ConditionFalse
+		if ready {
+			status = v1.ConditionTrue
+		}
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{
+				util.PipelineNameLabel: "simple-pipeline",
+				util.VertexNameLabel:   "p1",
+				util.ComponentLabel:    util.VertexComponent,
+			}},
+			Status: v1.PodStatus{Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: status}}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		pods    []*v1.Pod
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "lowest ready replica beyond nine",
+			pods: []*v1.Pod{
+				vertexPodOf("simple-pipeline-p1-10-aaaaa", true),
+				vertexPodOf("simple-pipeline-p1-2-zzzzz", true),
+				vertexPodOf("simple-pipeline-p1-1-bbbbb", false),
+			},
+			want: "simple-pipeline-p1-2-zzzzz",
+		},
+		{
+			name: "replica zero",
+			pods: []*v1.Pod{vertexPodOf("simple-pipeline-p1-1-aaaaa", true), vertexPodOf("simple-pipeline-p1-0-zzzzz", true)},
+			want: "simple-pipeline-p1-0-zzzzz",
+		},
+		{
+			name:    "no ready pods",
+			pods:    []*v1.Pod{vertexPodOf("simple-pipeline-p1-0-aaaaa", false)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset()
+			for _, pod := range tt.pods {
+				if _, err := kubeClient.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := vertexPod(context.Background(), kubeClient, "default", "simple-pipeline", "p1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("vertexPod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Pod != tt.want {
+				t.Errorf("vertexPod() = %q, want %q", got.Pod, tt.want)
+			}
+		})
+	}
+}
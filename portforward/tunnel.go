@@ -0,0 +1,102 @@
+package portforward
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Tunnel is an ephemeral forward of a free local port to a service, for commands that talk to the service themselves.
+// Like any forward of a session it reconnects when its pod is lost.
+type Tunnel struct {
+	// Address is the local host and port the tunnel listens on
+	Address string
+
+	stopCh    chan struct{}
+	done      chan error
+	closeOnce sync.Once
+	err       error
+}
+
+// OpenTunnel forwards a free local port to a ready pod behind the service and returns once the forward is ready and,
+// if healthPath is not empty, the service responds on it. The caller must Close the tunnel when it is done with it.
+func OpenTunnel(ctx context.Context, restConfig *rest.Config, kubeClient kubernetes.Interface, namespace string, serviceName string, servicePort int, healthPath string, log *zap.Logger) (*Tunnel, error) {
+	endpoint, err := ResolveService(ctx, kubeClient, namespace, serviceName, servicePort)
+	if err != nil {
+		return nil, err
+	}
+
+	localPort, err := FreePort()
+	if err != nil {
+		return nil, err
+	}
+
+	forward := &Forward{
+		Name:       serviceName,
+		Service:    serviceName,
+		HealthPath: healthPath,
+		// Reconnects can happen long after ctx is done
+		Resolve: func() (Endpoint, error) {
+			return ResolveService(context.Background(), kubeClient, namespace, serviceName, servicePort)
+		},
+		Request: &APodRequest{
+			RestConfig: restConfig,
+			Pod: v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      endpoint.Pod,
+					Namespace: namespace,
+				},
+			},
+			LocalPort: localPort,
+			PodPort:   endpoint.Port,
+			// Every connection would be announced on the output, which belongs to the caller
+			Streams: genericiooptions.IOStreams{Out: io.Discard, ErrOut: os.Stderr},
+		},
+	}
+
+	t := &Tunnel{
+		Address: fmt.Sprintf("localhost:%d", localPort),
+		stopCh:  make(chan struct{}),
+		done:    make(chan error, 1),
+	}
+
+	readyCh := make(chan struct{})
+	var readyOnce sync.Once
+	go func() {
+		t.done <- RunSession([]*Forward{forward}, t.stopCh, log, func() { readyOnce.Do(func() { close(readyCh) }) })
+	}()
+
+	select {
+	case <-readyCh:
+		return t, nil
+	case err := <-t.done:
+		return nil, err
+	case <-ctx.Done():
+		_ = t.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// URL returns the http url of the tunnel's local address
+func (t *Tunnel) URL() string {
+	return "http://" + t.Address
+}
+
+// Close stops the forward and waits for it to shut down, it is safe to call more than once
+func (t *Tunnel) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.stopCh)
+		t.err = <-t.done
+	})
+
+	return t.err
+}
@@ -1,152 +1,83 @@
 package report
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"os"
 
 	"github.com/ayildirim21/numaflow-perfman/util"
 )
 
+// DataSourceName is the name of the Prometheus data source perfman dashboards read from
+const DataSourceName = "Numaflow-PerfMan-Prometheus"
+
 type DashboardResponse struct {
-	ID    int    `json:"id"`
-	UID   string `json:"uid"`
-	Title string `json:"title"`
-	URL   string `json:"url"`
+	ID      int    `json:"id"`
+	UID     string `json:"uid"`
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Version int    `json:"version"`
 }
 
 func ReadJSONFile(filePath string) ([]byte, error) {
 	return os.ReadFile(filePath)
 }
 
-func CreateDashboard(grafanaURL, auth string, dashboardData []byte) (DashboardResponse, error) {
+// CreateDashboard creates the dashboard wrapped in dashboardData, as POSTed to /api/dashboards/db
+func (c *GrafanaClient) CreateDashboard(ctx context.Context, dashboardData []byte) (DashboardResponse, error) {
 	var response DashboardResponse
-	createURL := grafanaURL + "/api/dashboards/db"
-	req, err := http.NewRequest("POST", createURL, bytes.NewBuffer(dashboardData))
-	if err != nil {
-		return response, err
-	}
-	req.Header.Add("Authorization", "Basic "+auth)
-	req.Header.Add("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return response, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return response, err
-	}
-
-	if err := json.Unmarshal(body, &response); err != nil {
-		return response, fmt.Errorf("error parsing JSON response: %v", err)
-	}
-
-	return response, nil
+	err := c.do(ctx, http.MethodPost, "/api/dashboards/db", dashboardData, &response)
+	return response, err
 }
 
-func FetchDashboard(grafanaURL, auth, dashboardID string) ([]byte, error) {
-	// dashboardURL := fmt.Sprintf("%s/api/dashboards/db/%s", grafanaURL, dashboardName)
-	dashboardURL := fmt.Sprintf("%s/api/dashboards/uid/%s", grafanaURL, dashboardID)
-
-	req, _ := http.NewRequest("GET", dashboardURL, nil)
-	req.Header.Add("Authorization", "Basic "+auth)
-	req.Header.Add("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
+// FetchDashboard returns the dashboard with the given uid, wrapped with its metadata as Grafana returns it
+func (c *GrafanaClient) FetchDashboard(ctx context.Context, uid string) ([]byte, error) {
+	var dashboard json.RawMessage
+	if err := c.do(ctx, http.MethodGet, "/api/dashboards/uid/"+url.PathEscape(uid), nil, &dashboard); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	return io.ReadAll(resp.Body)
+	return dashboard, nil
 }
 
-func CreateSnapshot(grafanaURL, auth string, dashboardData []byte) (string, error) {
-	snapshotURL := grafanaURL + "/api/snapshots"
-	req, _ := http.NewRequest("POST", snapshotURL, bytes.NewBuffer(dashboardData))
-	req.Header.Add("Authorization", "Basic "+auth)
-	req.Header.Add("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
+// CreateSnapshot creates a snapshot of the dashboard and returns its url
+func (c *GrafanaClient) CreateSnapshot(ctx context.Context, dashboardData []byte) (string, error) {
 	var result struct {
 		URL string `json:"url"`
 	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("error parsing JSON response: %v", err)
+	if err := c.do(ctx, http.MethodPost, "/api/snapshots", dashboardData, &result); err != nil {
+		return "", err
 	}
 	if result.URL == "" {
 		return "", fmt.Errorf("snapshot URL not found in response")
 	}
+
 	return result.URL, nil
 }
 
-// CreateGrafanaDataSource assumes there is a prometheus server running on http://localhost:9090
-// and creates a new Grafana data source called Numaflow-PerfMan-Prometheus connecting to prometheus.
-// It returns the uid of the created data source.
-func CreateGrafanaDataSource(grafanaURL, auth string) (string, error) {
-
+// CreateDataSource creates the Prometheus data source called DataSourceName, reading from the Prometheus perfman
+// installs in the cluster. It returns the uid of the created data source, or ErrAlreadyExists.
+func (c *GrafanaClient) CreateDataSource(ctx context.Context) (string, error) {
 	dataSource := map[string]interface{}{
-		"name":      "Numaflow-PerfMan-Prometheus",
+		"name":      DataSourceName,
 		"type":      "prometheus",
-		"url":       fmt.Sprintf("http://%s:9090", util.PrometheusPFServiceName),
+		"url":       fmt.Sprintf("http://%s:%d", util.PrometheusPFServiceName, util.PrometheusServicePort),
 		"access":    "proxy",
 		"isDefault": false,
 	}
 
-	data, err := json.Marshal(dataSource)
-	if err != nil {
-		return "", err
-	}
-
-	dataSourceURL := grafanaURL + "/api/datasources"
-	req, _ := http.NewRequest("POST", dataSourceURL, bytes.NewBuffer(data))
-	req.Header.Add("Authorization", "Basic "+auth)
-	req.Header.Add("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	if resp.StatusCode >= 300 {
-		return "", fmt.Errorf("failed to fetch data sources: %s", string(body))
-	}
-
-	result := struct {
+	var result struct {
 		Datasource struct {
 			UID string `json:"uid"`
 		} `json:"datasource"`
-		Message string `json:"message"`
-	}{}
-
-	err = json.Unmarshal(body, &result)
-	if err != nil {
-		return "", fmt.Errorf("failed to unmarshal JSON response: %s", err)
 	}
+	if err := c.do(ctx, http.MethodPost, "/api/datasources", dataSource, &result); err != nil {
+		return "", err
+	}
+
 	return result.Datasource.UID, nil
 }
 
@@ -156,40 +87,12 @@ type GrafanaDatasource struct {
 	Name string `json:"name"`
 }
 
-// FetchGrafanaDataSourceUID fetches the UID of an existing data source, given the name.
-func FetchGrafanaDataSourceUID(grafanaURL, auth string) (string, error) {
-	dataSourceURL := grafanaURL + "/api/datasources"
-	req, _ := http.NewRequest("GET", dataSourceURL, nil)
-	req.Header.Add("Authorization", "Basic "+auth)
-	req.Header.Add("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+// FetchDataSourceUID returns the uid of the data source with the given name, or ErrNotFound
+func (c *GrafanaClient) FetchDataSourceUID(ctx context.Context, name string) (string, error) {
+	var ds GrafanaDatasource
+	if err := c.do(ctx, http.MethodGet, "/api/datasources/name/"+url.PathEscape(name), nil, &ds); err != nil {
 		return "", err
 	}
 
-	if resp.StatusCode >= 300 {
-		return "", fmt.Errorf("failed to create data source: %s", string(body))
-	}
-
-	var dataSources []GrafanaDatasource
-	err = json.Unmarshal(body, &dataSources)
-	if err != nil {
-		return "", fmt.Errorf("failed to unmarshal JSON response: %s", err)
-	}
-
-	for _, ds := range dataSources {
-		if ds.Name == "Numaflow-PerfMan-Prometheus" {
-			return ds.UID, nil
-		}
-	}
-
-	return "", fmt.Errorf("data source not found: %s", "Numaflow-PerfMan-Prometheus")
+	return ds.UID, nil
 }
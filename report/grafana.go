@@ -0,0 +1,210 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Errors the Grafana API answers with that callers can branch on, an *APIError wraps the one matching its status
+var (
+	ErrAlreadyExists = errors.New("already exists")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrNotFound      = errors.New("not found")
+)
+
+// APIError is a response of the Grafana API with a non-2xx status
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("grafana %s %s failed with status %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// Unwrap maps the status to ErrUnauthorized, ErrNotFound or ErrAlreadyExists
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		// Grafana answers 409 for duplicate data sources and 412 for dashboards that already exist
+		return ErrAlreadyExists
+	}
+
+	return nil
+}
+
+// Auth authenticates requests to Grafana
+type Auth interface {
+	Apply(req *http.Request)
+}
+
+// BasicAuth authenticates with a user and password
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a BasicAuth) Apply(req *http.Request) {
+	req.SetBasicAuth(a.Username, a.Password)
+}
+
+// TokenAuth authenticates with a service account token or API key
+type TokenAuth struct {
+	Token string
+}
+
+func (a TokenAuth) Apply(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+}
+
+// GrafanaClient talks to the Grafana HTTP API. Requests that fail to connect are retried with exponential backoff,
+// as are idempotent requests that fail in flight or are answered with 429 or a 5xx gateway status. A POST that
+// may have reached Grafana is never sent again, it could create the same object twice.
+type GrafanaClient struct {
+	URL        string
+	Auth       Auth
+	HTTPClient *http.Client
+	// Retries is how often a request is retried, Backoff the delay before the first retry, doubled for each one after
+	Retries int
+	Backoff time.Duration
+}
+
+// NewGrafanaClient returns a client for the Grafana server at baseURL
+func NewGrafanaClient(baseURL string, auth Auth) *GrafanaClient {
+	return &GrafanaClient{
+		URL:        baseURL,
+		Auth:       auth,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		Retries:    3,
+		Backoff:    500 * time.Millisecond,
+	}
+}
+
+// do sends body, marshalled to json unless it is already raw bytes, and decodes the response into result if it is not nil
+func (c *GrafanaClient) do(ctx context.Context, method string, path string, body interface{}, result interface{}) error {
+	var data []byte
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		data = b
+	default:
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to marshal grafana request: %w", err)
+		}
+	}
+
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		respBody, err := c.send(ctx, method, path, data)
+		if err == nil {
+			if result == nil {
+				return nil
+			}
+			if err := json.Unmarshal(respBody, result); err != nil {
+				return fmt.Errorf("failed to parse grafana response to %s %s: %w", method, path, err)
+			}
+			return nil
+		}
+
+		if attempt == c.Retries || !retryable(method, err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *GrafanaClient) send(ctx context.Context, method string, path string, data []byte) ([]byte, error) {
+	var reqBody io.Reader
+	if data != nil {
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.URL+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build grafana request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Auth != nil {
+		c.Auth.Apply(req)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach grafana: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read grafana response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{Method: method, Path: path, StatusCode: resp.StatusCode, Message: string(respBody)}
+		// Grafana explains errors in a json message, the raw body is kept if it does not
+		var msg struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(respBody, &msg) == nil && msg.Message != "" {
+			apiErr.Message = msg.Message
+		}
+		return nil, apiErr
+	}
+
+	return respBody, nil
+}
+
+// retryable reports whether a request that failed with err may succeed when sent again. Only idempotent
+// methods are retried once the request may have been written, other methods only when the connection failed.
+func retryable(method string, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		// Failing to reach grafana after connecting, the request may or may not have been received
+		return true
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
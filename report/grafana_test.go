@@ -0,0 +1,122 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestAPIErrorUnwrap(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{status: http.StatusUnauthorized, want: ErrUnauthorized},
+		{status: http.StatusForbidden, want: ErrUnauthorized},
+		{status: http.StatusNotFound, want: ErrNotFound},
+		{status: http.StatusConflict, want: ErrAlreadyExists},
+		{status: http.StatusPreconditionFailed, want: ErrAlreadyExists},
+		{status: http.StatusBadRequest, want: nil},
+		{status: http.StatusInternalServerError, want: nil},
+	}
+
+	sentinels := []error{ErrUnauthorized, ErrNotFound, ErrAlreadyExists}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", &APIError{Method: http.MethodGet, Path: "/api/test", StatusCode: tt.status})
+			for _, sentinel := range sentinels {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+					t.Errorf("errors.Is(status %d, %v) = %v, want %v", tt.status, sentinel, got, !got)
+				}
+			}
+		})
+	}
+}
+
+// transportError is how send reports a request that failed in the http client
+func transportError(method string, err error) error {
+	return fmt.Errorf("failed to reach grafana: %w", &url.Error{Op: method, URL: "http://grafana/api/test", Err: err})
+}
+
+func TestRetryable(t *testing.T) {
+	dialErr := transportError(http.MethodPost, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED})
+	readErr := transportError(http.MethodPost, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET})
+	apiErr := func(method string, status int) error {
+		return &APIError{Method: method, Path: "/api/test", StatusCode: status}
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		err    error
+		want   bool
+	}{
+		{name: "get refused", method: http.MethodGet, err: dialErr, want: true},
+		{name: "post refused", method: http.MethodPost, err: dialErr, want: true},
+		{name: "get reset", method: http.MethodGet, err: readErr, want: true},
+		{name: "put reset", method: http.MethodPut, err: readErr, want: true},
+		{name: "post reset", method: http.MethodPost, err: readErr, want: false},
+		{name: "get too many requests", method: http.MethodGet, err: apiErr(http.MethodGet, http.StatusTooManyRequests), want: true},
+		{name: "get bad gateway", method: http.MethodGet, err: apiErr(http.MethodGet, http.StatusBadGateway), want: true},
+		{name: "put unavailable", method: http.MethodPut, err: apiErr(http.MethodPut, http.StatusServiceUnavailable), want: true},
+		{name: "get gateway timeout", method: http.MethodGet, err: apiErr(http.MethodGet, http.StatusGatewayTimeout), want: true},
+		{name: "post bad gateway", method: http.MethodPost, err: apiErr(http.MethodPost, http.StatusBadGateway), want: false},
+		{name: "post unavailable", method: http.MethodPost, err: apiErr(http.MethodPost, http.StatusServiceUnavailable), want: false},
+		{name: "post gateway timeout", method: http.MethodPost, err: apiErr(http.MethodPost, http.StatusGatewayTimeout), want: false},
+		{name: "get internal error", method: http.MethodGet, err: apiErr(http.MethodGet, http.StatusInternalServerError), want: false},
+		{name: "get not found", method: http.MethodGet, err: apiErr(http.MethodGet, http.StatusNotFound), want: false},
+		{name: "get unauthorized", method: http.MethodGet, err: apiErr(http.MethodGet, http.StatusUnauthorized), want: false},
+		{name: "get cancelled", method: http.MethodGet, err: transportError(http.MethodGet, context.Canceled), want: false},
+		{name: "post dial deadline", method: http.MethodPost, err: transportError(http.MethodPost, &net.OpError{Op: "dial", Err: context.DeadlineExceeded}), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.method, tt.err); got != tt.want {
+				t.Errorf("retryable(%s, %v) = %v, want %v", tt.method, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrafanaClientRetries(t *testing.T) {
+	tests := []struct {
+		method       string
+		wantRequests int32
+	}{
+		{method: http.MethodGet, wantRequests: 3},
+		{method: http.MethodPut, wantRequests: 3},
+		{method: http.MethodPost, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			client := NewGrafanaClient(server.URL, nil)
+			client.Retries, client.Backoff = 2, time.Millisecond
+
+			err := client.do(context.Background(), tt.method, "/api/test", map[string]string{}, nil)
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+				t.Fatalf("do() error = %v, want a 503 APIError", err)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("%s sent %d requests, want %d", tt.method, got, tt.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SetTimeWindow pins the time range of a dashboard to [from, to] and selects the given pipeline in its
+// pipeline variable, so that a snapshot shows exactly one benchmark run
+func SetTimeWindow(dashboardData []byte, from time.Time, to time.Time, pipelineName string) ([]byte, error) {
+	var wrapper map[string]interface{}
+	if err := json.Unmarshal(dashboardData, &wrapper); err != nil {
+		return nil, fmt.Errorf("error parsing dashboard JSON: %v", err)
+	}
+
+	dashboard, ok := wrapper["dashboard"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("dashboard JSON has no dashboard object")
+	}
+
+	dashboard["time"] = map[string]interface{}{
+		"from": from.UTC().Format(time.RFC3339),
+		"to":   to.UTC().Format(time.RFC3339),
+	}
+
+	if templating, ok := dashboard["templating"].(map[string]interface{}); ok {
+		variables, _ := templating["list"].([]interface{})
+		for _, v := range variables {
+			variable, ok := v.(map[string]interface{})
+			if !ok || variable["name"] != "pipeline" {
+				continue
+			}
+			variable["current"] = map[string]interface{}{
+				"selected": true,
+				"text":     pipelineName,
+				"value":    pipelineName,
+			}
+		}
+	}
+
+	return json.Marshal(wrapper)
+}
@@ -0,0 +1,185 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Folder and dashboard perfman keeps its reporting dashboard in. Their uids are stable, so report can run any number of times.
+const (
+	FolderUID    = "perfman"
+	FolderTitle  = "perfman"
+	DashboardUID = "perfman-dashboard"
+)
+
+// Folder is a Grafana dashboard folder
+type Folder struct {
+	ID    int    `json:"id"`
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+}
+
+// EnsureFolder returns the folder with the given uid, creating it first if it does not exist
+func (c *GrafanaClient) EnsureFolder(ctx context.Context, uid string, title string) (Folder, error) {
+	var folder Folder
+	err := c.do(ctx, http.MethodGet, "/api/folders/"+url.PathEscape(uid), nil, &folder)
+	if !errors.Is(err, ErrNotFound) {
+		return folder, err
+	}
+
+	err = c.do(ctx, http.MethodPost, "/api/folders", map[string]string{"uid": uid, "title": title}, &folder)
+	if errors.Is(err, ErrAlreadyExists) {
+		// Created concurrently, or another folder already has the title
+		if getErr := c.do(ctx, http.MethodGet, "/api/folders/"+url.PathEscape(uid), nil, &folder); getErr == nil {
+			return folder, nil
+		}
+	}
+
+	return folder, err
+}
+
+// DashboardOptions sets where and how a dashboard is upserted
+type DashboardOptions struct {
+	UID       string
+	FolderUID string
+	// Tags are added to those of the dashboard
+	Tags []string
+	// Message describes the change in the dashboard's version history
+	Message string
+}
+
+// PrepareDashboard sets the uid, folder and tags of the dashboard wrapped in dashboardData, and marks it to overwrite
+// any dashboard with the same uid
+func PrepareDashboard(dashboardData []byte, opts DashboardOptions) ([]byte, error) {
+	var wrapper map[string]interface{}
+	if err := json.Unmarshal(dashboardData, &wrapper); err != nil {
+		return nil, fmt.Errorf("error parsing dashboard JSON: %v", err)
+	}
+
+	dashboard, ok := wrapper["dashboard"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("dashboard JSON has no dashboard object")
+	}
+
+	// An id would address a dashboard of one particular Grafana, the uid is what stays stable
+	dashboard["id"] = nil
+	dashboard["uid"] = opts.UID
+
+	tags, _ := dashboard["tags"].([]interface{})
+	for _, tag := range opts.Tags {
+		if !containsTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	dashboard["tags"] = tags
+
+	delete(wrapper, "folderId")
+	wrapper["folderUid"] = opts.FolderUID
+	wrapper["overwrite"] = true
+	if opts.Message != "" {
+		wrapper["message"] = opts.Message
+	}
+
+	return json.Marshal(wrapper)
+}
+
+func containsTag(tags []interface{}, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UpsertDashboard creates the dashboard wrapped in dashboardData, as prepared by PrepareDashboard, or overwrites the one
+// with its uid. With keepHistory, a dashboard about to be overwritten with different panels is first copied to
+// <uid>-v<version>, so its layout stays reachable beyond the versions Grafana keeps.
+func (c *GrafanaClient) UpsertDashboard(ctx context.Context, dashboardData []byte, keepHistory bool) (DashboardResponse, error) {
+	if keepHistory {
+		if err := c.archiveDashboard(ctx, dashboardData); err != nil {
+			return DashboardResponse{}, fmt.Errorf("failed to keep the previous dashboard: %w", err)
+		}
+	}
+
+	return c.CreateDashboard(ctx, dashboardData)
+}
+
+type dashboardWrapper struct {
+	Dashboard map[string]interface{} `json:"dashboard"`
+	Meta      struct {
+		FolderUID string `json:"folderUid"`
+	} `json:"meta"`
+}
+
+func (c *GrafanaClient) archiveDashboard(ctx context.Context, dashboardData []byte) error {
+	var next dashboardWrapper
+	if err := json.Unmarshal(dashboardData, &next); err != nil {
+		return fmt.Errorf("error parsing dashboard JSON: %v", err)
+	}
+	uid, _ := next.Dashboard["uid"].(string)
+
+	existingData, err := c.FetchDashboard(ctx, uid)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var existing dashboardWrapper
+	if err := json.Unmarshal(existingData, &existing); err != nil {
+		return fmt.Errorf("error parsing dashboard JSON: %v", err)
+	}
+
+	same, err := sameLayout(existing.Dashboard, next.Dashboard)
+	if err != nil || same {
+		return err
+	}
+
+	version, _ := existing.Dashboard["version"].(float64)
+	archive := existing.Dashboard
+	archive["id"] = nil
+	archive["uid"] = fmt.Sprintf("%s-v%d", uid, int(version))
+	archive["title"] = fmt.Sprintf("%v (v%d)", archive["title"], int(version))
+	tags, _ := archive["tags"].([]interface{})
+	archive["tags"] = append(tags, "archived")
+
+	data, err := json.Marshal(map[string]interface{}{
+		"dashboard": archive,
+		"folderUid": existing.Meta.FolderUID,
+		"overwrite": true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal dashboard: %w", err)
+	}
+
+	_, err = c.CreateDashboard(ctx, data)
+	return err
+}
+
+// sameLayout compares the panels and variables of two dashboards
+func sameLayout(a map[string]interface{}, b map[string]interface{}) (bool, error) {
+	for _, key := range []string{"panels", "templating"} {
+		// Marshalling sorts keys, which makes the encodings comparable
+		encodedA, err := json.Marshal(a[key])
+		if err != nil {
+			return false, err
+		}
+		encodedB, err := json.Marshal(b[key])
+		if err != nil {
+			return false, err
+		}
+		if !bytes.Equal(encodedA, encodedB) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,160 @@
+package report
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPrepareDashboard(t *testing.T) {
+	tests := []struct {
+		name      string
+		dashboard string
+		tags      []string
+		wantTags  []interface{}
+	}{
+		{
+			name:      "no existing tags",
+			dashboard: `{"dashboard": {"id": 7, "title": "perfman"}, "folderId": 3}`,
+			tags:      []string{"perfman", "numaflow:v1.2.1"},
+			wantTags:  []interface{}{"perfman", "numaflow:v1.2.1"},
+		},
+		{
+			name:      "tags already on the dashboard",
+			dashboard: `{"dashboard": {"title": "perfman", "tags": ["perfman", "team"]}}`,
+			tags:      []string{"perfman", "numaflow:v1.2.1"},
+			wantTags:  []interface{}{"perfman", "team", "numaflow:v1.2.1"},
+		},
+		{
+			name:      "repeated tags",
+			dashboard: `{"dashboard": {"title": "perfman", "tags": []}}`,
+			tags:      []string{"perfman", "perfman", "numaflow:v1.2.1", "perfman"},
+			wantTags:  []interface{}{"perfman", "numaflow:v1.2.1"},
+		},
+		{
+			name:      "no tags to add",
+			dashboard: `{"dashboard": {"title": "perfman", "tags": ["team"]}}`,
+			tags:      nil,
+			wantTags:  []interface{}{"team"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := PrepareDashboard([]byte(tt.dashboard), DashboardOptions{
+				UID:       DashboardUID,
+				FolderUID: FolderUID,
+				Tags:      tt.tags,
+				Message:   "perfman report",
+			})
+			if err != nil {
+				t.Fatalf("PrepareDashboard() error = %v", err)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			dashboard := got["dashboard"].(map[string]interface{})
+
+			if !reflect.DeepEqual(dashboard["tags"], tt.wantTags) {
+				t.Errorf("tags = %v, want %v", dashboard["tags"], tt.wantTags)
+			}
+			if dashboard["uid"] != DashboardUID || dashboard["id"] != nil {
+				t.Errorf("uid, id = %v, %v, want %s, nil", dashboard["uid"], dashboard["id"], DashboardUID)
+			}
+			if _, found := got["folderId"]; found || got["folderUid"] != FolderUID {
+				t.Errorf("folderId, folderUid = %v, %v, want none, %s", got["folderId"], got["folderUid"], FolderUID)
+			}
+			if got["overwrite"] != true || got["message"] != "perfman report" {
+				t.Errorf("overwrite, message = %v, %v, want true, perfman report", got["overwrite"], got["message"])
+			}
+		})
+	}
+}
+
+func TestPrepareDashboardInvalid(t *testing.T) {
+	for _, data := range []string{`not json`, `{"title": "perfman"}`, `{"dashboard": []}`} {
+		if _, err := PrepareDashboard([]byte(data), DashboardOptions{UID: DashboardUID}); err == nil {
+			t.Errorf("PrepareDashboard(%s) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestSameLayout(t *testing.T) {
+	parse := func(s string) map[string]interface{} {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want bool
+	}{
+		{
+			name: "identical",
+			a:    `{"panels": [{"id": 1, "title": "cpu"}], "templating": {"list": []}}`,
+			b:    `{"panels": [{"id": 1, "title": "cpu"}], "templating": {"list": []}}`,
+			want: true,
+		},
+		{
+			name: "keys in a different order",
+			a:    `{"panels": [{"id": 1, "title": "cpu"}]}`,
+			b:    `{"panels": [{"title": "cpu", "id": 1}]}`,
+			want: true,
+		},
+		{
+			name: "other fields differ",
+			a:    `{"title": "perfman", "version": 3, "tags": ["a"], "panels": []}`,
+			b:    `{"title": "renamed", "version": 4, "tags": ["b"], "panels": []}`,
+			want: true,
+		},
+		{
+			name: "panel changed",
+			a:    `{"panels": [{"id": 1, "title": "cpu"}]}`,
+			b:    `{"panels": [{"id": 1, "title": "memory"}]}`,
+			want: false,
+		},
+		{
+			name: "panel added",
+			a:    `{"panels": [{"id": 1}]}`,
+			b:    `{"panels": [{"id": 1}, {"id": 2}]}`,
+			want: false,
+		},
+		{
+			name: "panels reordered",
+			a:    `{"panels": [{"id": 1}, {"id": 2}]}`,
+			b:    `{"panels": [{"id": 2}, {"id": 1}]}`,
+			want: false,
+		},
+		{
+			name: "variable changed",
+			a:    `{"panels": [], "templating": {"list": [{"name": "pipeline"}]}}`,
+			b:    `{"panels": [], "templating": {"list": [{"name": "vertex"}]}}`,
+			want: false,
+		},
+		{
+			name: "templating missing on one side",
+			a:    `{"panels": [], "templating": {"list": []}}`,
+			b:    `{"panels": []}`,
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sameLayout(parse(tt.a), parse(tt.b))
+			if err != nil {
+				t.Fatalf("sameLayout() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("sameLayout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ayildirim21/numaflow-perfman/util"
+)
+
+// CreateControllerMetricsService exposes the metrics endpoint of the numaflow controller through a service
+// a service monitor can select, as numaflow does not ship one. The service selects the controller pods with
+// the selector of the controller deployment, so it follows however numaflow was installed.
+func CreateControllerMetricsService(kubeClient *kubernetes.Clientset, log *zap.Logger) error {
+	services := kubeClient.CoreV1().Services(util.NumaflowNamespace)
+	if _, err := services.Get(context.TODO(), util.NumaflowControllerMetricsService, metav1.GetOptions{}); err == nil {
+		log.Info("Resource already exists, skipping creation", zap.String("resource-name", util.NumaflowControllerMetricsService))
+		return nil
+	} else if !kerrors.IsNotFound(err) {
+		return fmt.Errorf("failed to check if resource exists: %w", err)
+	}
+
+	deployment, err := kubeClient.AppsV1().Deployments(util.NumaflowNamespace).Get(context.TODO(), util.NumaflowControllerName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get numaflow controller deployment: %w", err)
+	}
+	if deployment.Spec.Selector == nil || len(deployment.Spec.Selector.MatchLabels) == 0 {
+		return fmt.Errorf("numaflow controller deployment has no label selector to select its pods with")
+	}
+
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   util.NumaflowControllerMetricsService,
+			Labels: map[string]string{"app.kubernetes.io/name": util.NumaflowControllerMetricsService},
+		},
+		Spec: v1.ServiceSpec{
+			Selector: deployment.Spec.Selector.MatchLabels,
+			Ports: []v1.ServicePort{{
+				Name:       "metrics",
+				Port:       util.NumaflowControllerMetricsPort,
+				TargetPort: intstr.FromInt32(util.NumaflowControllerMetricsPort),
+			}},
+		},
+	}
+	if _, err := services.Create(context.TODO(), service, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	log.Info("Applied resource", zap.String("resource-name", util.NumaflowControllerMetricsService))
+	return nil
+}
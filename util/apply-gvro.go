@@ -21,14 +21,20 @@ type GVRObject struct {
 	Namespace string
 }
 
-func readYamlFile(filename string) (*unstructured.Unstructured, error) {
+// ReadYamlFile reads a single yaml manifest into an unstructured object
+func ReadYamlFile(filename string) (*unstructured.Unstructured, error) {
 	yamlFile, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read yaml file: %w", err)
 	}
 
+	return ParseYaml(yamlFile)
+}
+
+// ParseYaml parses a single yaml manifest into an unstructured object
+func ParseYaml(data []byte) (*unstructured.Unstructured, error) {
 	var obj unstructured.Unstructured
-	if err := yaml.Unmarshal(yamlFile, &obj.Object); err != nil {
+	if err := yaml.Unmarshal(data, &obj.Object); err != nil {
 		return nil, fmt.Errorf("failed to unmasrhsal into object: %w", err)
 	}
 
@@ -36,11 +42,16 @@ func readYamlFile(filename string) (*unstructured.Unstructured, error) {
 }
 
 func (gvro *GVRObject) CreateResource(filename string, dynamicClient *dynamic.DynamicClient, logger *zap.Logger) error {
-	obj, err := readYamlFile(filename)
+	obj, err := ReadYamlFile(filename)
 	if err != nil {
 		return fmt.Errorf("failed to retrieve configuration information: %w", err)
 	}
 
+	return gvro.CreateObject(obj, dynamicClient, logger)
+}
+
+// CreateObject creates obj unless a resource with the same name already exists
+func (gvro *GVRObject) CreateObject(obj *unstructured.Unstructured, dynamicClient *dynamic.DynamicClient, logger *zap.Logger) error {
 	gvr := schema.GroupVersionResource{Group: gvro.Group, Version: gvro.Version, Resource: gvro.Resource}
 	resourceInterface := dynamicClient.Resource(gvr).Namespace(gvro.Namespace)
 
@@ -6,9 +6,45 @@ const (
 	PerfmanNamespace  = "default"
 	NumaflowNamespace = "numaflow-system"
 
+	// Name of the numaflow controller deployment
+	NumaflowControllerName = "numaflow-controller"
+
+	// Service perfman sets up in front of the numaflow controller so Prometheus can scrape it, and the port it serves metrics on
+	NumaflowControllerMetricsService = "numaflow-controller-metrics"
+	NumaflowControllerMetricsPort    = 9090
+
+	// Labels numaflow sets on the pods of a pipeline
+	PipelineNameLabel = "numaflow.numaproj.io/pipeline-name"
+	VertexNameLabel   = "numaflow.numaproj.io/vertex-name"
+	ComponentLabel    = "app.kubernetes.io/component"
+
+	// Values of the component label of a pipeline's daemon and vertex pods
+	DaemonComponent = "daemon"
+	VertexComponent = "vertex"
+
+	// Service of the numaflow UI server and the ports numaflow serves on, all of them over https
+	NumaflowServerServiceName = "numaflow-server"
+	NumaflowServerPort        = 8443
+	DaemonServicePort         = 4327
+	VertexMetricsPort         = 2469
+
+	// Name of the inter-step buffer service perfman sets up, and the label numaflow sets on its pods
+	ISBServiceName      = "default"
+	ISBServiceNameLabel = "numaflow.numaproj.io/isbsvc-name"
+
 	// Service names to use for port forwarding
 	PrometheusPFServiceName = "perfman-kube-prometheus-prometheus"
 	GrafanaPFServiceName    = "perfman-grafana"
 
+	// Service ports of Prometheus and Grafana, forwarded to whichever container port they target
+	PrometheusServicePort = 9090
+	GrafanaServicePort    = 80
+
 	GrafanaPassword = "admin"
+
+	// Pipeline applied when no other is given
+	DefaultPipelineFile = "default/pipeline.yaml"
+
+	// Directory benchmark run records are written to
+	RunRecordDir = "runs"
 )
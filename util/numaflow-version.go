@@ -0,0 +1,26 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NumaflowVersion returns the image tag of the numaflow controller running on the cluster, e.g. v1.2.1
+func NumaflowVersion(kubeClient *kubernetes.Clientset) (string, error) {
+	deployment, err := kubeClient.AppsV1().Deployments(NumaflowNamespace).Get(context.TODO(), NumaflowControllerName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get numaflow controller deployment: %w", err)
+	}
+
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if i := strings.LastIndex(container.Image, ":"); i >= 0 && !strings.Contains(container.Image[i:], "/") {
+			return container.Image[i+1:], nil
+		}
+	}
+
+	return "", fmt.Errorf("unable to determine numaflow version from controller image")
+}
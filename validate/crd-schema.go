@@ -0,0 +1,72 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var crdGvr = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+// SchemaFetcher retrieves the openAPIV3Schema of installed CRDs, caching every schema it has already fetched
+type SchemaFetcher struct {
+	DynamicClient *dynamic.DynamicClient
+	cache         map[string]map[string]interface{}
+}
+
+// SchemaFor returns the openAPIV3Schema of the CRD serving the given apiVersion and kind
+func (sf *SchemaFetcher) SchemaFor(apiVersion string, kind string) (map[string]interface{}, error) {
+	key := apiVersion + "/" + kind
+	if s, ok := sf.cache[key]; ok {
+		return s, nil
+	}
+
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid apiVersion %q: %w", apiVersion, err)
+	}
+
+	crds, err := sf.DynamicClient.Resource(crdGvr).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list custom resource definitions: %w", err)
+	}
+
+	for _, crd := range crds.Items {
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		crdKind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+		if group != gv.Group || crdKind != kind {
+			continue
+		}
+
+		versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+		for _, v := range versions {
+			version, ok := v.(map[string]interface{})
+			if !ok || version["name"] != gv.Version {
+				continue
+			}
+
+			openAPISchema, found, err := unstructured.NestedMap(version, "schema", "openAPIV3Schema")
+			if err != nil || !found {
+				return nil, fmt.Errorf("crd %s has no openAPIV3Schema for version %s", crd.GetName(), gv.Version)
+			}
+
+			if sf.cache == nil {
+				sf.cache = make(map[string]map[string]interface{})
+			}
+			sf.cache[key] = openAPISchema
+			return openAPISchema, nil
+		}
+
+		return nil, fmt.Errorf("crd %s does not serve version %s", crd.GetName(), gv.Version)
+	}
+
+	return nil, fmt.Errorf("no crd installed for %s %s", apiVersion, kind)
+}
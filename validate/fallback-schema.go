@@ -0,0 +1,47 @@
+package validate
+
+import (
+	"embed"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed schemas/*.yaml
+var schemasFS embed.FS
+
+// fallbackSchemas lists the built-in schemas by apiVersion and kind, for CRDs that install without describing their spec
+var fallbackSchemas = map[string]string{
+	"numaflow.numaproj.io/v1alpha1/Pipeline": "schemas/pipeline.yaml",
+}
+
+// FallbackSchema returns perfman's built-in openAPIV3Schema for the given apiVersion and kind, or nil if it has none
+func FallbackSchema(apiVersion string, kind string) (map[string]interface{}, error) {
+	file, ok := fallbackSchemas[apiVersion+"/"+kind]
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := schemasFS.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read built-in schema: %w", err)
+	}
+
+	var s map[string]interface{}
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse built-in schema %s: %w", file, err)
+	}
+
+	return s, nil
+}
+
+// describesSpec reports whether a schema describes the fields of the spec, rather than preserving them unchecked
+func describesSpec(s map[string]interface{}) bool {
+	spec, ok := properties(s)["spec"].(map[string]interface{})
+	if !ok {
+		return !preservesUnknownFields(s)
+	}
+	_, additionalIsSchema := spec["additionalProperties"].(map[string]interface{})
+
+	return len(properties(spec)) > 0 || additionalIsSchema || !preservesUnknownFields(spec)
+}
@@ -0,0 +1,362 @@
+package validate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a single problem found in a manifest, located by file, line and column
+type Issue struct {
+	File     string
+	Line     int
+	Column   int
+	Path     string
+	Severity string
+	Message  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s: %s", i.File, i.Line, i.Column, i.Severity, i.Path, i.Message)
+}
+
+// SchemaFunc returns the openAPIV3Schema for objects of the given apiVersion and kind
+type SchemaFunc func(apiVersion string, kind string) (map[string]interface{}, error)
+
+// HasErrors reports whether any of the issues is an error rather than a warning
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateManifest validates every yaml document in data against the schema returned by schemaFor.
+// The filename is only used to locate issues.
+func ValidateManifest(filename string, data []byte, schemaFor SchemaFunc) ([]Issue, error) {
+	var issues []Issue
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+		}
+
+		// Empty documents, as between two consecutive separators, decode to a null scalar
+		root := resolve(&doc)
+		if root == nil || root.ShortTag() == "!!null" {
+			continue
+		}
+
+		if root.Kind != yaml.MappingNode {
+			issues = append(issues, newIssue(filename, root, "", SeverityError, "manifest must be a yaml mapping"))
+			continue
+		}
+
+		apiVersion := mappingValue(root, "apiVersion")
+		kind := mappingValue(root, "kind")
+		if apiVersion == nil || kind == nil {
+			issues = append(issues, newIssue(filename, root, "", SeverityError, "manifest must set apiVersion and kind"))
+			continue
+		}
+
+		openAPISchema, err := schemaFor(apiVersion.Value, kind.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		// Numaflow's CRDs preserve their spec without describing it, check it against a built-in schema instead
+		if !describesSpec(openAPISchema) {
+			fallback, err := FallbackSchema(apiVersion.Value, kind.Value)
+			if err != nil {
+				return nil, err
+			}
+			if fallback != nil {
+				issues = append(issues, newIssue(filename, root, kind.Value, SeverityWarning,
+					"installed crd does not describe the spec, validating against perfman's built-in schema"))
+				openAPISchema = fallback
+			}
+		}
+
+		v := validator{file: filename}
+		v.validateObject(root, openAPISchema, "", true)
+		issues = append(issues, v.issues...)
+	}
+
+	return issues, nil
+}
+
+type validator struct {
+	file   string
+	issues []Issue
+}
+
+func (v *validator) report(node *yaml.Node, path string, format string, args ...interface{}) {
+	v.issues = append(v.issues, newIssue(v.file, node, path, SeverityError, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) warn(node *yaml.Node, path string, format string, args ...interface{}) {
+	v.issues = append(v.issues, newIssue(v.file, node, path, SeverityWarning, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) validate(node *yaml.Node, s map[string]interface{}, path string) {
+	node = resolve(node)
+	if node == nil || node.ShortTag() == "!!null" {
+		return
+	}
+
+	if b, _ := s["x-kubernetes-int-or-string"].(bool); b {
+		if node.Kind != yaml.ScalarNode || (node.ShortTag() != "!!int" && node.ShortTag() != "!!str") {
+			v.report(node, path, "expected an integer or a string")
+		}
+		return
+	}
+
+	switch s["type"] {
+	case "object":
+		v.validateObject(node, s, path, false)
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			v.report(node, path, "expected a list, got %s", describe(node))
+			return
+		}
+		items, _ := s["items"].(map[string]interface{})
+		for i, item := range node.Content {
+			v.validate(item, items, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case "string":
+		v.expectScalar(node, s, path, "a string", "!!str")
+	case "integer":
+		v.expectScalar(node, s, path, "an integer", "!!int")
+	case "number":
+		v.expectScalar(node, s, path, "a number", "!!int", "!!float")
+	case "boolean":
+		v.expectScalar(node, s, path, "a boolean", "!!bool")
+	}
+}
+
+func (v *validator) validateObject(node *yaml.Node, s map[string]interface{}, path string, isRoot bool) {
+	if node.Kind != yaml.MappingNode {
+		v.report(node, path, "expected an object, got %s", describe(node))
+		return
+	}
+
+	// Type metadata is validated by the API server itself, and CRD schemas rarely describe it
+	embedded, _ := s["x-kubernetes-embedded-resource"].(bool)
+	skipTypeMeta := isRoot || embedded
+
+	props := properties(s)
+	additional, additionalIsSchema := s["additionalProperties"].(map[string]interface{})
+	if len(props) == 0 && !additionalIsSchema && preservesUnknownFields(s) {
+		if len(node.Content) > 0 {
+			v.warn(node, path, "schema does not describe this object, its fields were not checked")
+		}
+		return
+	}
+	present := make(map[string]bool)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		key := keyNode.Value
+		present[key] = true
+		fieldPath := joinPath(path, key)
+
+		if skipTypeMeta && (key == "apiVersion" || key == "kind" || key == "metadata") {
+			continue
+		}
+		if isRoot && key == "status" {
+			continue
+		}
+
+		if prop, ok := props[key].(map[string]interface{}); ok {
+			v.validate(valueNode, prop, fieldPath)
+			continue
+		}
+
+		if additionalIsSchema {
+			v.validate(valueNode, additional, fieldPath)
+			continue
+		}
+
+		if preservesUnknownFields(s) {
+			v.warn(keyNode, fieldPath, "field %q is not described by the schema and was not checked", key)
+			continue
+		}
+
+		if suggestion := closestField(key, props); suggestion != "" {
+			v.report(keyNode, fieldPath, "unknown field %q, did you mean %q?", key, suggestion)
+		} else {
+			v.report(keyNode, fieldPath, "unknown field %q", key)
+		}
+	}
+
+	required, _ := s["required"].([]interface{})
+	for _, r := range required {
+		name, _ := r.(string)
+		if name != "" && !present[name] {
+			v.report(node, path, "missing required field %q", name)
+		}
+	}
+}
+
+func (v *validator) expectScalar(node *yaml.Node, s map[string]interface{}, path string, expected string, tags ...string) {
+	if node.Kind != yaml.ScalarNode {
+		v.report(node, path, "expected %s, got %s", expected, describe(node))
+		return
+	}
+
+	matched := false
+	for _, tag := range tags {
+		if node.ShortTag() == tag {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		v.report(node, path, "expected %s, got %q", expected, node.Value)
+		return
+	}
+
+	enum, _ := s["enum"].([]interface{})
+	if len(enum) == 0 {
+		return
+	}
+	allowed := make([]string, 0, len(enum))
+	for _, e := range enum {
+		if fmt.Sprint(e) == node.Value {
+			return
+		}
+		allowed = append(allowed, fmt.Sprint(e))
+	}
+	v.report(node, path, "unsupported value %q, must be one of: %s", node.Value, strings.Join(allowed, ", "))
+}
+
+func newIssue(file string, node *yaml.Node, path string, severity string, message string) Issue {
+	if path == "" {
+		path = "."
+	}
+
+	return Issue{
+		File:     file,
+		Line:     node.Line,
+		Column:   node.Column,
+		Path:     path,
+		Severity: severity,
+		Message:  message,
+	}
+}
+
+// resolve unwraps document and alias nodes
+func resolve(node *yaml.Node) *yaml.Node {
+	for node != nil {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			if len(node.Content) == 0 {
+				return nil
+			}
+			node = node.Content[0]
+		case yaml.AliasNode:
+			node = node.Alias
+		default:
+			return node
+		}
+	}
+
+	return nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return resolve(node.Content[i+1])
+		}
+	}
+
+	return nil
+}
+
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "an object"
+	case yaml.SequenceNode:
+		return "a list"
+	default:
+		return fmt.Sprintf("%q", node.Value)
+	}
+}
+
+func properties(s map[string]interface{}) map[string]interface{} {
+	props, _ := s["properties"].(map[string]interface{})
+	return props
+}
+
+func preservesUnknownFields(s map[string]interface{}) bool {
+	preserve, _ := s["x-kubernetes-preserve-unknown-fields"].(bool)
+	return preserve
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// closestField suggests the known property closest to an unknown field name, if one is close enough to be a typo
+func closestField(name string, props map[string]interface{}) string {
+	candidates := make([]string, 0, len(props))
+	for p := range props {
+		candidates = append(candidates, p)
+	}
+	sort.Strings(candidates)
+
+	best := ""
+	bestDistance := 3
+	for _, c := range candidates {
+		if d := levenshtein(strings.ToLower(name), strings.ToLower(c)); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+
+	return best
+}
+
+func levenshtein(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
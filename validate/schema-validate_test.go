@@ -0,0 +1,311 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+const widgetSchema = `
+type: object
+properties:
+  apiVersion:
+    type: string
+  kind:
+    type: string
+  metadata:
+    type: object
+  spec:
+    type: object
+    required: [name]
+    properties:
+      name:
+        type: string
+      replicas:
+        type: integer
+      ratio:
+        type: number
+      enabled:
+        type: boolean
+      mode:
+        type: string
+        enum: [fast, slow]
+      port:
+        x-kubernetes-int-or-string: true
+      labels:
+        type: object
+        additionalProperties:
+          type: string
+      items:
+        type: array
+        items:
+          type: object
+          properties:
+            id:
+              type: integer
+      opaque:
+        type: object
+        x-kubernetes-preserve-unknown-fields: true
+      loose:
+        type: object
+        x-kubernetes-preserve-unknown-fields: true
+        properties:
+          known:
+            type: string
+  status:
+    type: object
+`
+
+// unstructuredSchema is how numaflow's CRDs describe their objects, preserving the spec without describing it
+const unstructuredSchema = `
+type: object
+properties:
+  apiVersion:
+    type: string
+  kind:
+    type: string
+  metadata:
+    type: object
+  spec:
+    type: object
+    x-kubernetes-preserve-unknown-fields: true
+  status:
+    type: object
+    x-kubernetes-preserve-unknown-fields: true
+`
+
+func schemaFunc(t *testing.T, schemas map[string]string) SchemaFunc {
+	return func(apiVersion string, kind string) (map[string]interface{}, error) {
+		data, ok := schemas[apiVersion+"/"+kind]
+		if !ok {
+			return nil, errors.New("no crd installed for " + apiVersion + " " + kind)
+		}
+
+		var s map[string]interface{}
+		if err := yaml.Unmarshal([]byte(data), &s); err != nil {
+			t.Fatal(err)
+		}
+		return s, nil
+	}
+}
+
+// widget is a manifest whose spec has its required name on line 6, followed by the given spec fields from line 7
+func widget(fields ...string) string {
+	manifest := "apiVersion: test/v1\nkind: Widget\nmetadata:\n  name: w\nspec:\n  name: w\n"
+	for _, f := range fields {
+		manifest += "  " + f + "\n"
+	}
+
+	return manifest
+}
+
+func TestValidateManifest(t *testing.T) {
+	schemas := schemaFunc(t, map[string]string{
+		"test/v1/Widget":                                       widgetSchema,
+		"numaflow.numaproj.io/v1alpha1/Pipeline":               unstructuredSchema,
+		"numaflow.numaproj.io/v1alpha1/InterStepBufferService": unstructuredSchema,
+	})
+
+	tests := []struct {
+		name     string
+		manifest string
+		want     []string
+	}{
+		{
+			name: "valid",
+			manifest: widget("replicas: 3", "ratio: 1", "enabled: true", "mode: fast", "port: 8080",
+				"labels: {team: perf}", "items: [{id: 1}, {id: 2}]"),
+			want: nil,
+		},
+		{
+			name:     "unknown fields",
+			manifest: widget("replica: 3", "colour: red"),
+			want: []string{
+				`test.yaml:7:3: error: spec.replica: unknown field "replica", did you mean "replicas"?`,
+				`test.yaml:8:3: error: spec.colour: unknown field "colour"`,
+			},
+		},
+		{
+			name:     "missing required field",
+			manifest: "apiVersion: test/v1\nkind: Widget\nspec:\n  replicas: 3\n",
+			want:     []string{`test.yaml:4:3: error: spec: missing required field "name"`},
+		},
+		{
+			name:     "scalar type mismatches",
+			manifest: widget("replicas: three", "ratio: high", "enabled: yes", "mode: {speed: fast}"),
+			want: []string{
+				`test.yaml:7:13: error: spec.replicas: expected an integer, got "three"`,
+				`test.yaml:8:10: error: spec.ratio: expected a number, got "high"`,
+				`test.yaml:9:12: error: spec.enabled: expected a boolean, got "yes"`,
+				`test.yaml:10:9: error: spec.mode: expected a string, got an object`,
+			},
+		},
+		{
+			name:     "enum mismatch",
+			manifest: widget("mode: medium"),
+			want:     []string{`test.yaml:7:9: error: spec.mode: unsupported value "medium", must be one of: fast, slow`},
+		},
+		{
+			name:     "int or string",
+			manifest: widget("port: http", "port: 80", "port: true", "port: [80]"),
+			want: []string{
+				`test.yaml:9:9: error: spec.port: expected an integer or a string`,
+				`test.yaml:10:9: error: spec.port: expected an integer or a string`,
+			},
+		},
+		{
+			name:     "additional properties",
+			manifest: widget("labels: {team: perf, tier: 1}"),
+			want:     []string{`test.yaml:7:30: error: spec.labels.tier: expected a string, got "1"`},
+		},
+		{
+			name:     "arrays",
+			manifest: widget("items: [{id: 1}, {id: one}]", "items: one"),
+			want: []string{
+				`test.yaml:7:25: error: spec.items[1].id: expected an integer, got "one"`,
+				`test.yaml:8:10: error: spec.items: expected a list, got "one"`,
+			},
+		},
+		{
+			name:     "null values are not checked",
+			manifest: widget("replicas: null", "labels: ~"),
+			want:     nil,
+		},
+		{
+			name:     "preserved unknown fields",
+			manifest: widget("opaque: {a: 1}", "opaque: {}", "loose: {known: k, extra: 1}"),
+			want: []string{
+				`test.yaml:7:11: warning: spec.opaque: schema does not describe this object, its fields were not checked`,
+				`test.yaml:9:21: warning: spec.loose.extra: field "extra" is not described by the schema and was not checked`,
+			},
+		},
+		{
+			name: "root type metadata and status are skipped",
+			manifest: "apiVersion: test/v1\nkind: Widget\nmetadata:\n  name: w\n  anything: 1\nspec:\n  name: w\n" +
+				"status:\n  phase: Running\nextra: 1\n",
+			want: []string{`test.yaml:10:1: error: extra: unknown field "extra"`},
+		},
+		{
+			name: "undescribed pipeline spec falls back to the built-in schema",
+			manifest: "apiVersion: numaflow.numaproj.io/v1alpha1\nkind: Pipeline\nmetadata:\n  name: p\nspec:\n  vertices:\n" +
+				"    - name: p1\n      udf:\n        builtin:\n          nam: cat\n",
+			want: []string{
+				`test.yaml:1:1: warning: Pipeline: installed crd does not describe the spec, validating against perfman's built-in schema`,
+				`test.yaml:10:11: error: spec.vertices[0].udf.builtin.nam: unknown field "nam", did you mean "name"?`,
+				`test.yaml:10:11: error: spec.vertices[0].udf.builtin: missing required field "name"`,
+			},
+		},
+		{
+			name:     "undescribed spec without a built-in schema",
+			manifest: "apiVersion: numaflow.numaproj.io/v1alpha1\nkind: InterStepBufferService\nspec:\n  jetstream:\n    version: latest\n",
+			want:     []string{`test.yaml:4:3: warning: spec: schema does not describe this object, its fields were not checked`},
+		},
+		{
+			name:     "multiple documents",
+			manifest: widget() + "---\n---\n" + widget("replicas: three"),
+			want:     []string{`test.yaml:15:13: error: spec.replicas: expected an integer, got "three"`},
+		},
+		{
+			name:     "not a mapping",
+			manifest: "- apiVersion: test/v1\n- kind: Widget\n",
+			want:     []string{`test.yaml:1:1: error: .: manifest must be a yaml mapping`},
+		},
+		{
+			name:     "no apiVersion",
+			manifest: "kind: Widget\nspec:\n  name: w\n",
+			want:     []string{`test.yaml:1:1: error: .: manifest must set apiVersion and kind`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := ValidateManifest("test.yaml", []byte(tt.manifest), schemas)
+			if err != nil {
+				t.Fatalf("ValidateManifest() error = %v", err)
+			}
+
+			var got []string
+			for _, issue := range issues {
+				got = append(got, issue.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateManifest() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestValidateManifestErrors(t *testing.T) {
+	schemas := schemaFunc(t, map[string]string{"test/v1/Widget": widgetSchema})
+
+	tests := []struct {
+		name     string
+		manifest string
+	}{
+		{name: "invalid yaml", manifest: "spec: [unclosed\n"},
+		{name: "no schema", manifest: "apiVersion: test/v1\nkind: Gadget\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ValidateManifest("test.yaml", []byte(tt.manifest), schemas); err == nil {
+				t.Errorf("ValidateManifest() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestClosestField(t *testing.T) {
+	props := map[string]interface{}{"replicas": nil, "name": nil, "limits": nil, "scale": nil}
+
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{field: "nam", want: "name"},
+		{field: "replica", want: "replicas"},
+		{field: "Replicas", want: "replicas"},
+		{field: "limts", want: "limits"},
+		{field: "scal", want: "scale"},
+		{field: "sclae", want: "scale"},
+		{field: "image", want: ""},
+		{field: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			if got := closestField(tt.field, props); got != tt.want {
+				t.Errorf("closestField(%q) = %q, want %q", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{a: "", b: "", want: 0},
+		{a: "name", b: "name", want: 0},
+		{a: "", b: "name", want: 4},
+		{a: "nam", b: "name", want: 1},
+		{a: "nmae", b: "name", want: 2},
+		{a: "kitten", b: "sitting", want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := levenshtein(tt.a, tt.b); got != tt.want {
+				t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := levenshtein(tt.b, tt.a); got != tt.want {
+				t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}